@@ -0,0 +1,322 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// serveAdmin handles the administration of the albums. The SecurityFrontend
+// ensures the user is an admin and that forms carry the anti-CSRF token.
+func (web *WebInterface) serveAdmin(w http.ResponseWriter, r *http.Request, originalPath string) {
+	var collection, albumName, action string
+	collection, r.URL.Path = ShiftPath(r.URL.Path)
+	albumName, r.URL.Path = ShiftPath(r.URL.Path)
+	action, r.URL.Path = ShiftPath(r.URL.Path)
+
+	if r.URL.Path != "/" {
+		web.handleFileNotFound(w, r)
+		return
+	}
+
+	if collection == "tokens" {
+		web.serveAdminTokens(w, r, albumName, action, originalPath)
+		return
+	}
+
+	if r.Method == "POST" {
+		if collection != "album" || albumName == "" || action == "" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		web.handleAdminAction(w, r, albumName, action)
+		return
+	}
+
+	if !strings.HasSuffix(originalPath, "/") {
+		http.Redirect(w, r, originalPath+"/", http.StatusMovedPermanently)
+		return
+	}
+
+	if collection == "" {
+		web.handleAdminIndex(w, r)
+	} else if collection == "album" && albumName != "" && action == "" {
+		web.handleAdminAlbum(w, r, albumName)
+	} else {
+		web.handleFileNotFound(w, r)
+	}
+}
+
+func (web *WebInterface) handleAdminIndex(w http.ResponseWriter, r *http.Request) {
+	albums, err := web.MediaStore.ListAlbums()
+	if err != nil {
+		log.Printf("MediaStore.ListAlbums: %s", err)
+		web.handleError(w, r)
+		return
+	}
+	sort.Sort(sort.Reverse(albums))
+
+	err = web.AdminTemplate.Execute(w, struct {
+		I18n      I18n
+		CSRFToken string
+		Albums    []Album
+	}{
+		web.negotiateLanguage(w, r),
+		GetCSRFToken(r),
+		albums,
+	})
+	if err != nil {
+		log.Printf("Template.Execute: %s", err)
+		web.handleError(w, r)
+		return
+	}
+}
+
+func (web *WebInterface) handleAdminAlbum(w http.ResponseWriter, r *http.Request, albumName string) {
+	id := albumName
+	if albumName == "latest" {
+		albumName = ""
+	}
+
+	album, err := web.MediaStore.GetAlbum(albumName, false)
+	if errors.Is(err, ErrAlbumNotFound) {
+		web.handleFileNotFound(w, r)
+		return
+	} else if err != nil {
+		log.Printf("MediaStore.GetAlbum: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	err = web.AdminAlbumTemplate.Execute(w, struct {
+		I18n      I18n
+		CSRFToken string
+		AlbumID   string
+		Album     *Album
+	}{
+		web.negotiateLanguage(w, r),
+		GetCSRFToken(r),
+		id,
+		album,
+	})
+	if err != nil {
+		log.Printf("Template.Execute: %s", err)
+		web.handleError(w, r)
+		return
+	}
+}
+
+// recordAudit records an admin change with its outcome. The change being
+// made, a failure to record it can only be logged.
+func (web *WebInterface) recordAudit(entry AuditEntry) {
+	err := web.Audit.Record(entry)
+	if err != nil {
+		log.Printf("AuditLog.Record: %s (%s %s by %s: %s)", err, entry.Action, entry.Album, entry.User, entry.Result)
+	}
+}
+
+func (web *WebInterface) handleAdminAction(w http.ResponseWriter, r *http.Request, albumName string, action string) {
+	id := albumName
+	if albumName == "latest" {
+		albumName = ""
+	}
+
+	user := GetWebUser(r)
+	mediaId := r.PostFormValue("media")
+	entry := AuditEntry{User: user.String(), Action: action, Album: id, Media: mediaId}
+
+	var apply func() error
+	switch action {
+	case "rename":
+		title := strings.TrimSpace(r.PostFormValue("title"))
+		entry.Details = title
+		apply = func() error { return web.MediaStore.RenameAlbum(albumName, title) }
+	case "cover":
+		apply = func() error { return web.MediaStore.SetCover(albumName, mediaId) }
+	case "delete":
+		apply = func() error { return web.deleteMedia(albumName, mediaId) }
+	case "move":
+		direction := r.PostFormValue("direction")
+		entry.Details = direction
+		apply = func() error { return web.moveMedia(albumName, mediaId, direction) }
+	default:
+		web.handleFileNotFound(w, r)
+		return
+	}
+
+	err := apply()
+	entry.Result = auditResult(err)
+	web.recordAudit(entry)
+	if errors.Is(err, ErrAlbumNotFound) || errors.Is(err, ErrMediaNotFound) {
+		web.handleFileNotFound(w, r)
+		return
+	} else if errors.Is(err, errInvalidAdminAction) {
+		log.Printf("[%s] Cannot %s album '%s': %s", user, action, id, err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Printf("[%s] Cannot %s album '%s': %s", user, action, id, err)
+		web.handleError(w, r)
+		return
+	}
+
+	log.Printf("[%s] %s album '%s' (media = '%s', details = '%s')", user, action, id, mediaId, entry.Details)
+
+	// Albums can also be renamed from the admin index
+	if r.PostFormValue("from") == "index" {
+		http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/admin/album/"+id+"/", http.StatusSeeOther)
+}
+
+// deleteMedia deletes a media from its album and from the favorites and
+// messages referencing it
+func (web *WebInterface) deleteMedia(albumName string, mediaId string) error {
+	err := web.MediaStore.DeleteMedia(albumName, mediaId)
+	if err != nil {
+		return err
+	}
+
+	// The media is gone already, stale references are only logged
+	if web.Favorites != nil {
+		if err := web.Favorites.Forget(mediaId); err != nil {
+			log.Printf("FavoritesDB.Forget: %s", err)
+		}
+	}
+	if web.MessageDB != nil {
+		if err := web.MessageDB.Forget(mediaId); err != nil {
+			log.Printf("MessageDB.Forget: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// moveMedia moves a media one position up or down in an album
+func (web *WebInterface) moveMedia(albumName string, mediaId string, direction string) error {
+	album, err := web.MediaStore.GetAlbum(albumName, false)
+	if err != nil {
+		return err
+	}
+
+	position, err := findMedia(album.Media, mediaId)
+	if err != nil {
+		return err
+	}
+
+	if direction == "up" {
+		position--
+	} else if direction == "down" {
+		position++
+	} else {
+		return fmt.Errorf("%w: the direction must be either 'up' or 'down'", errInvalidAdminAction)
+	}
+
+	return web.MediaStore.MoveMedia(albumName, mediaId, position)
+}
+
+// serveAdminTokens handles the management of the API tokens
+func (web *WebInterface) serveAdminTokens(w http.ResponseWriter, r *http.Request, id string, action string, originalPath string) {
+	if web.APITokens == nil {
+		web.handleFileNotFound(w, r)
+		return
+	}
+
+	if r.Method == "POST" {
+		if id == "new" && action == "" {
+			web.handleCreateToken(w, r)
+		} else if id != "" && action == "revoke" {
+			web.handleRevokeToken(w, r, id)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	if !strings.HasSuffix(originalPath, "/") {
+		http.Redirect(w, r, originalPath+"/", http.StatusMovedPermanently)
+		return
+	}
+
+	if id != "" {
+		web.handleFileNotFound(w, r)
+		return
+	}
+
+	web.renderAdminTokens(w, r, "")
+}
+
+// renderAdminTokens lists the API tokens, along with the secret of the token
+// just created (if any)
+func (web *WebInterface) renderAdminTokens(w http.ResponseWriter, r *http.Request, secret string) {
+	// The secret is shown once and must not be kept by any cache
+	w.Header().Set("Cache-Control", "no-store")
+
+	err := web.AdminTokensTemplate.Execute(w, struct {
+		I18n      I18n
+		CSRFToken string
+		Tokens    []APIToken
+		Scopes    []string
+		Secret    string
+	}{
+		web.negotiateLanguage(w, r),
+		GetCSRFToken(r),
+		web.APITokens.List(),
+		[]string{ScopeRead, ScopeUpload, ScopeAdmin},
+		secret,
+	})
+	if err != nil {
+		log.Printf("Template.Execute: %s", err)
+		web.handleError(w, r)
+		return
+	}
+}
+
+func (web *WebInterface) handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	user := GetWebUser(r)
+	name := strings.TrimSpace(r.PostFormValue("name"))
+	scopes := r.PostForm["scope"]
+	if name == "" || len(scopes) == 0 || validateScopes(scopes) != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	_, secret, err := web.APITokens.Create(name, scopes, user.String())
+	web.recordAudit(AuditEntry{User: user.String(), Action: "create-token", Details: name + " (" + strings.Join(scopes, ", ") + ")", Result: auditResult(err)})
+	if errors.Is(err, errDuplicateToken) {
+		http.Error(w, "A token with this name already exists", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("[%s] Cannot create API token '%s': %s", user, name, err)
+		web.handleError(w, r)
+		return
+	}
+
+	log.Printf("[%s] created API token '%s' with scopes %s", user, name, strings.Join(scopes, ", "))
+	web.renderAdminTokens(w, r, secret)
+}
+
+func (web *WebInterface) handleRevokeToken(w http.ResponseWriter, r *http.Request, id string) {
+	user := GetWebUser(r)
+	ok, err := web.APITokens.Revoke(id)
+	result := auditResult(err)
+	if err == nil && !ok {
+		result = "unknown token"
+	}
+	web.recordAudit(AuditEntry{User: user.String(), Action: "revoke-token", Details: id, Result: result})
+	if err != nil {
+		log.Printf("[%s] Cannot revoke API token %s: %s", user, id, err)
+		web.handleError(w, r)
+		return
+	} else if !ok {
+		web.handleFileNotFound(w, r)
+		return
+	}
+
+	log.Printf("[%s] revoked API token %s", user, id)
+	http.Redirect(w, r, "/admin/tokens/", http.StatusSeeOther)
+}
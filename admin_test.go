@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func readAuditLog(t *testing.T, audit *AuditLog) []AuditEntry {
+	yamlData, err := ioutil.ReadFile(audit.Path)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(): error %s", err)
+	}
+
+	var entries []AuditEntry
+	err = yaml.UnmarshalStrict(yamlData, &entries)
+	if err != nil {
+		t.Fatalf("yaml.UnmarshalStrict(): error %s", err)
+	}
+	return entries
+}
+
+func TestAdminActionAudit(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+	createTestAlbum(t, store, "My album")
+	id := addTestPhoto(t, store, time.Now(), "", "")
+
+	audit, err := InitAuditLog(filepath.Join(tmp.RootDir, "audit.yaml"))
+	if err != nil {
+		t.Fatalf("InitAuditLog(): %s", err)
+	}
+
+	web := &WebInterface{MediaStore: store, Audit: audit, I18n: NewWebCatalog("en", map[string]I18n{"en": {}})}
+	user := &WebUser{Username: "john@example.test", Type: TypeOidcUser, Scopes: []string{ScopeAdmin}}
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/admin/album/latest/cover/", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		web.ServeHTTP(w, withWebUser(r, user, ""))
+		return w
+	}
+
+	w := post(url.Values{"media": {"unknown"}})
+	assert.Equal(t, w.Code, http.StatusNotFound, "unknown media")
+	w = post(url.Values{"media": {id}})
+	assert.Equal(t, w.Code, http.StatusSeeOther, "the cover is changed")
+
+	entries := readAuditLog(t, audit)
+	assert.Equal(t, len(entries), 2, "both changes are audited")
+	assert.Equal(t, entries[0].Result, "Unknown media 'unknown'", "the failure is recorded")
+	assert.Equal(t, entries[1].Result, "done", "the success is recorded")
+	assert.Equal(t, entries[1].User, "OIDC:john@example.test", "the admin is recorded")
+}
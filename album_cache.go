@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// albumCache keeps the albums loaded by the queries spanning all albums, so
+// that their chat.yaml is only read again when they change
+type albumCache struct {
+	lock    sync.Mutex
+	entries map[string]albumCacheEntry
+}
+
+// An albumCacheEntry is valid as long as the album folder (files added or
+// removed), its meta.yaml and its chat.yaml have not been modified
+type albumCacheEntry struct {
+	version albumVersion
+	album   Album
+
+	// The default cover (see setDefaultCover) depends on the favorites: it
+	// is chosen again when they change
+	defaultCover bool
+	favorites    int
+
+	// Folded text of the album title and of each media, as searched
+	titleText string
+	mediaText []string
+}
+
+type albumVersion struct {
+	folder   time.Time
+	meta     time.Time
+	chat     time.Time
+	chatSize int64
+}
+
+func (store *MediaStore) getAlbumVersion(folder string) (albumVersion, error) {
+	var version albumVersion
+	stat, err := os.Stat(filepath.Join(store.StoreLocation, folder))
+	if err != nil {
+		return version, err
+	}
+	version.folder = stat.ModTime()
+
+	// meta.yaml and chat.yaml may be missing in a new album
+	if stat, err := os.Stat(filepath.Join(store.StoreLocation, folder, "meta.yaml")); err == nil {
+		version.meta = stat.ModTime()
+	}
+	if stat, err := os.Stat(filepath.Join(store.StoreLocation, folder, "chat.yaml")); err == nil {
+		version.chat = stat.ModTime()
+		version.chatSize = stat.Size()
+	}
+
+	return version, nil
+}
+
+// getCachedAlbum returns an album with its media, from the cache when it
+// has not changed since it was loaded
+func (store *MediaStore) getCachedAlbum(folder string) (*Album, error) {
+	entry, err := store.getCacheEntry(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.album.copy(), nil
+}
+
+// getCacheEntry returns the cache entry of an album, loading the album again
+// if it has changed. The album of the entry must not be modified.
+func (store *MediaStore) getCacheEntry(folder string) (albumCacheEntry, error) {
+	version, err := store.getAlbumVersion(folder)
+	if err != nil {
+		return albumCacheEntry{}, err
+	}
+
+	var favorites int
+	if store.Favorites != nil {
+		favorites = store.Favorites.Version()
+	}
+
+	store.cache.lock.Lock()
+	entry, ok := store.cache.entries[folder]
+	store.cache.lock.Unlock()
+	if ok && entry.version == version {
+		if entry.defaultCover && entry.favorites != favorites {
+			entry.album.CoverMedia = Media{}
+			entry.album.setDefaultCover(store.Favorites.Counts())
+			entry.favorites = favorites
+			store.putCacheEntry(folder, entry)
+		}
+
+		return entry, nil
+	}
+
+	// Albums deleted since the last miss are no longer listed: forget them
+	store.evictDeletedAlbums()
+
+	metadata, err := store.GetAlbum(folder, true)
+	if err != nil {
+		return albumCacheEntry{}, err
+	}
+
+	album, err := store.GetAlbum(folder, false)
+	if err != nil {
+		return albumCacheEntry{}, err
+	}
+
+	entry = albumCacheEntry{
+		version:      version,
+		album:        *album,
+		defaultCover: metadata.CoverMedia.IsZero(),
+		favorites:    favorites,
+		titleText:    foldText(album.Title),
+	}
+	for _, media := range album.Media {
+		entry.mediaText = append(entry.mediaText, mediaSearchText(album.Title, media))
+	}
+	store.putCacheEntry(folder, entry)
+
+	return entry, nil
+}
+
+func (store *MediaStore) putCacheEntry(folder string, entry albumCacheEntry) {
+	store.cache.lock.Lock()
+	defer store.cache.lock.Unlock()
+
+	if store.cache.entries == nil {
+		store.cache.entries = make(map[string]albumCacheEntry)
+	}
+	store.cache.entries[folder] = entry
+}
+
+// evictDeletedAlbums removes the cache entries of the albums whose folder
+// no longer exists
+func (store *MediaStore) evictDeletedAlbums() {
+	store.cache.lock.Lock()
+	defer store.cache.lock.Unlock()
+
+	for folder := range store.cache.entries {
+		if !fileExists(filepath.Join(store.StoreLocation, folder)) {
+			delete(store.cache.entries, folder)
+		}
+	}
+}
+
+// copy returns an album whose list of media can be changed without altering
+// the original one
+func (album Album) copy() *Album {
+	album.Media = append([]Media(nil), album.Media...)
+	return &album
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+type apiAlbum struct {
+	ID    string     `json:"id"`
+	Title string     `json:"title"`
+	Date  time.Time  `json:"date"`
+	URL   string     `json:"url"`
+	Cover *apiMedia  `json:"cover,omitempty"`
+	Media []apiMedia `json:"media,omitempty"`
+}
+
+type apiMedia struct {
+	ID      string     `json:"id"`
+	Type    string     `json:"type"`
+	Caption string     `json:"caption"`
+	Date    time.Time  `json:"date"`
+	TakenAt *time.Time `json:"takenAt,omitempty"`
+	Tags    []string   `json:"tags,omitempty"`
+	URL     string     `json:"url"`
+	Files   []apiFile  `json:"files"`
+}
+
+type apiFile struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func (web *WebInterface) serveAPI(w http.ResponseWriter, r *http.Request) {
+	var version, collection, albumName, kind, mediaId, files, filename string
+	version, r.URL.Path = ShiftPath(r.URL.Path)
+	collection, r.URL.Path = ShiftPath(r.URL.Path)
+	albumName, r.URL.Path = ShiftPath(r.URL.Path)
+	kind, r.URL.Path = ShiftPath(r.URL.Path)
+	mediaId, r.URL.Path = ShiftPath(r.URL.Path)
+	files, r.URL.Path = ShiftPath(r.URL.Path)
+	filename, r.URL.Path = ShiftPath(r.URL.Path)
+
+	if version != "v1" || (collection != "albums" && collection != "locations" && collection != "search") || r.URL.Path != "/" {
+		web.apiError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	baseURL := GetBasePath(r) + "/api/v1/albums/"
+	switch {
+	case collection == "search" && albumName == "":
+		web.handleAPISearch(w, r, baseURL)
+	case collection == "search":
+		web.apiError(w, "Not found", http.StatusNotFound)
+	case collection == "locations" && albumName == "":
+		web.handleAPIGetLocations(w, r, "")
+	case collection == "locations":
+		web.apiError(w, "Not found", http.StatusNotFound)
+	case albumName == "":
+		web.handleAPIListAlbums(w, r, baseURL)
+	case kind == "":
+		web.handleAPIGetAlbum(w, r, baseURL, albumName)
+	case kind == "locations" && mediaId == "":
+		web.handleAPIGetLocations(w, r, albumName)
+	case kind == "media" && mediaId != "" && files == "":
+		web.handleAPIGetMedia(w, r, baseURL, albumName, mediaId)
+	case kind == "media" && mediaId != "" && files == "files" && filename != "":
+		web.handleAPIGetFile(w, r, albumName, mediaId, filename)
+	default:
+		web.apiError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+func (web *WebInterface) apiError(w http.ResponseWriter, message string, status int) {
+	web.apiResponse(w, apiError{Error: message}, status)
+}
+
+func (web *WebInterface) apiResponse(w http.ResponseWriter, response interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		log.Printf("json.Encode: %s", err)
+	}
+}
+
+// getAPIAlbum loads an album, answering with the right status code if
+// it cannot be loaded.
+func (web *WebInterface) getAPIAlbum(w http.ResponseWriter, albumName string) (*Album, bool) {
+	if albumName == "latest" {
+		albumName = ""
+	}
+
+	album, err := web.MediaStore.GetAlbum(albumName, false)
+	if errors.Is(err, ErrAlbumNotFound) {
+		web.apiError(w, "Album not found", http.StatusNotFound)
+		return nil, false
+	} else if err != nil {
+		log.Printf("MediaStore.GetAlbum: %s", err)
+		web.apiError(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return album, true
+}
+
+func newAPIAlbum(album Album, baseURL string) apiAlbum {
+	id := album.ID
+	if id == "" {
+		id = "latest"
+	}
+
+	albumURL := baseURL + url.PathEscape(id) + "/"
+	result := apiAlbum{
+		ID:    id,
+		Title: album.Title,
+		Date:  album.Date,
+		URL:   albumURL,
+	}
+
+	if !album.CoverMedia.IsZero() {
+		cover := newAPIMedia(album.CoverMedia, albumURL)
+		result.Cover = &cover
+	}
+
+	return result
+}
+
+func newAPIMedia(media Media, albumURL string) apiMedia {
+	mediaURL := albumURL + "media/" + url.PathEscape(media.ID) + "/"
+	result := apiMedia{
+		ID:      media.ID,
+		Type:    media.Type,
+		Caption: media.Caption,
+		Date:    media.Date,
+		Tags:    media.Tags,
+		URL:     mediaURL,
+		Files:   make([]apiFile, len(media.Files)),
+	}
+
+	if !media.TakenAt.IsZero() {
+		takenAt := media.TakenAt
+		result.TakenAt = &takenAt
+	}
+
+	for i, file := range media.Files {
+		result.Files[i] = apiFile{Name: file, URL: mediaURL + "files/" + url.PathEscape(file)}
+	}
+
+	return result
+}
+
+func (web *WebInterface) handleAPIListAlbums(w http.ResponseWriter, r *http.Request, baseURL string) {
+	albums, err := web.MediaStore.ListAlbums()
+	if err != nil {
+		log.Printf("MediaStore.ListAlbums: %s", err)
+		web.apiError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	sort.Sort(sort.Reverse(albums))
+	result := make([]apiAlbum, 0, len(albums))
+	for _, album := range albums {
+		result = append(result, newAPIAlbum(album, baseURL))
+	}
+
+	web.apiResponse(w, result, http.StatusOK)
+}
+
+func (web *WebInterface) handleAPIGetAlbum(w http.ResponseWriter, r *http.Request, baseURL string, albumName string) {
+	album, ok := web.getAPIAlbum(w, albumName)
+	if !ok {
+		return
+	}
+
+	result := newAPIAlbum(*album, baseURL)
+	result.Media = make([]apiMedia, len(album.Media))
+	for i, media := range album.Media {
+		result.Media[i] = newAPIMedia(media, result.URL)
+	}
+
+	web.apiResponse(w, result, http.StatusOK)
+}
+
+func (web *WebInterface) handleAPIGetMedia(w http.ResponseWriter, r *http.Request, baseURL string, albumName string, mediaId string) {
+	album, ok := web.getAPIAlbum(w, albumName)
+	if !ok {
+		return
+	}
+
+	for _, media := range album.Media {
+		if media.ID == mediaId {
+			web.apiResponse(w, newAPIMedia(media, newAPIAlbum(*album, baseURL).URL), http.StatusOK)
+			return
+		}
+	}
+
+	web.apiError(w, "Media not found", http.StatusNotFound)
+}
+
+func (web *WebInterface) handleAPIGetFile(w http.ResponseWriter, r *http.Request, albumName string, mediaId string, filename string) {
+	album, ok := web.getAPIAlbum(w, albumName)
+	if !ok {
+		return
+	}
+
+	// Only the files of the media can be retrieved
+	for _, media := range album.Media {
+		if media.ID != mediaId {
+			continue
+		}
+
+		for _, file := range media.Files {
+			if file == filename {
+				web.handleGetMedia(w, r, album.ID, filename)
+				return
+			}
+		}
+	}
+
+	web.apiError(w, "File not found", http.StatusNotFound)
+}
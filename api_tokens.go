@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+var errDuplicateToken = errors.New("A token with this name already exists")
+
+type APIToken struct {
+	ID        string    `yaml:"id"`
+	Name      string    `yaml:"name"`
+	Hash      string    `yaml:"hash"` // SHA-256 of the secret part of the token
+	Scopes    []string  `yaml:"scopes"`
+	CreatedBy string    `yaml:"createdBy"`
+	Created   time.Time `yaml:"created"`
+}
+
+// APITokenStore holds the personal API tokens. Only their hash is stored,
+// the tokens themselves are given once, upon creation.
+type APITokenStore struct {
+	Path string
+
+	// Map token ids to tokens
+	Db map[string]APIToken
+
+	lock sync.Mutex
+}
+
+func InitAPITokenStore(path string) (*APITokenStore, error) {
+	db := make(map[string]APIToken)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	yamlData, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(yamlData, &db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &APITokenStore{Path: path, Db: db}, nil
+}
+
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if scope != ScopeRead && scope != ScopeUpload && scope != ScopeAdmin {
+			return fmt.Errorf("Unknown scope '%s'", scope)
+		}
+	}
+	return nil
+}
+
+// Create generates a new token and returns it, along with its metadata.
+// The returned string is the only copy of the token.
+func (store *APITokenStore) Create(name string, scopes []string, createdBy string) (APIToken, string, error) {
+	err := validateScopes(scopes)
+	if err != nil {
+		return APIToken{}, "", err
+	}
+
+	id, err := newRandomSecret(6)
+	if err != nil {
+		return APIToken{}, "", err
+	}
+
+	secret, err := newRandomSecret(32)
+	if err != nil {
+		return APIToken{}, "", err
+	}
+
+	token := APIToken{
+		ID:        id.String(),
+		Name:      name,
+		Hash:      secret.Hashed(),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		Created:   time.Now(),
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	// The name of the token identifies the API user in the logs
+	for _, existing := range store.Db {
+		if existing.Name == name {
+			return APIToken{}, "", fmt.Errorf("%w: '%s'", errDuplicateToken, name)
+		}
+	}
+
+	store.Db[token.ID] = token
+	err = store.save()
+	if err != nil {
+		delete(store.Db, token.ID)
+		return APIToken{}, "", err
+	}
+
+	return token, token.ID + "." + secret.String(), nil
+}
+
+// List returns the tokens, oldest first
+func (store *APITokenStore) List() []APIToken {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	tokens := make([]APIToken, 0, len(store.Db))
+	for _, token := range store.Db {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Created.Before(tokens[j].Created)
+	})
+
+	return tokens
+}
+
+// Revoke deletes a token and returns false if there is no such token
+func (store *APITokenStore) Revoke(id string) (bool, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if _, ok := store.Db[id]; !ok {
+		return false, nil
+	}
+
+	delete(store.Db, id)
+	return true, store.save()
+}
+
+// Validate returns the metadata of a token if it is valid
+func (store *APITokenStore) Validate(token string) (APIToken, bool) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return APIToken{}, false
+	}
+
+	secret, err := secretFromHex(parts[1])
+	if err != nil {
+		return APIToken{}, false
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	apiToken, ok := store.Db[parts[0]]
+	if !ok || subtle.ConstantTimeCompare([]byte(apiToken.Hash), []byte(secret.Hashed())) != 1 {
+		return APIToken{}, false
+	}
+
+	return apiToken, true
+}
+
+func (store *APITokenStore) save() error {
+	yamlData, err := yaml.Marshal(store.Db)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(store.Path, store.Path+".bak")
+	if err != nil {
+		log.Printf("Cannot perform a backup of the token store before update: %s", err)
+	}
+
+	return ioutil.WriteFile(store.Path, yamlData, 0600)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestAPITokenStore(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	file := filepath.Join(tmp.RootDir, "tokens.yaml")
+	store, err := InitAPITokenStore(file)
+	if err != nil {
+		t.Errorf("InitAPITokenStore(): %s", err)
+	}
+
+	created, token, err := store.Create("backup", []string{ScopeRead}, "john")
+	if err != nil {
+		t.Errorf("Create(): %s", err)
+	}
+	assert.Equal(t, store.Db[created.ID].Hash != token, true, "token is not stored in clear text")
+
+	_, _, err = store.Create("backup", []string{ScopeRead}, "john")
+	if err == nil {
+		t.Errorf("Create(): duplicate token name accepted")
+	}
+	_, _, err = store.Create("other", []string{"write"}, "john")
+	if err == nil {
+		t.Errorf("Create(): unknown scope accepted")
+	}
+
+	// Reload the store from disk
+	store, err = InitAPITokenStore(file)
+	if err != nil {
+		t.Errorf("InitAPITokenStore(): %s", err)
+	}
+
+	validated, ok := store.Validate(token)
+	assert.Equal(t, ok, true, "token is valid")
+	assert.Equal(t, validated.Name, "backup", "token name")
+	assert.Equal(t, validated.Scopes, []string{ScopeRead}, "token scopes")
+
+	_, ok = store.Validate(created.ID + ".0011223344")
+	assert.Equal(t, ok, false, "wrong secret")
+	_, ok = store.Validate("garbage")
+	assert.Equal(t, ok, false, "invalid token")
+
+	revoked, err := store.Revoke(created.ID)
+	if err != nil {
+		t.Errorf("Revoke(): %s", err)
+	}
+	assert.Equal(t, revoked, true, "token revoked")
+	_, ok = store.Validate(token)
+	assert.Equal(t, ok, false, "revoked token is not valid anymore")
+}
+
+func TestAdminTokens(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	tokens, err := InitAPITokenStore(filepath.Join(tmp.RootDir, "tokens.yaml"))
+	if err != nil {
+		t.Fatalf("InitAPITokenStore(): %s", err)
+	}
+	audit, err := InitAuditLog(filepath.Join(tmp.RootDir, "audit.yaml"))
+	if err != nil {
+		t.Fatalf("InitAuditLog(): %s", err)
+	}
+	tokensTemplate, err := getTemplate(http.Dir("web"), "/admin-tokens.html.template", "admin-tokens")
+	if err != nil {
+		t.Fatalf("getTemplate(): error %s", err)
+	}
+
+	web := &WebInterface{
+		I18n:                NewWebCatalog("en", map[string]I18n{"en": {}}),
+		Audit:               audit,
+		APITokens:           tokens,
+		AdminTokensTemplate: tokensTemplate,
+	}
+	user := &WebUser{Username: "john@example.test", Type: TypeOidcUser, Scopes: []string{ScopeAdmin}}
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		web.ServeHTTP(w, withWebUser(r, user, ""))
+		return w
+	}
+
+	w := post("/admin/tokens/new/", url.Values{"name": {"backup"}, "scope": {ScopeRead}})
+	assert.Equal(t, w.Code, http.StatusOK, "the token is created")
+	list := tokens.List()
+	assert.Equal(t, len(list), 1, "one token")
+	assert.Equal(t, list[0].CreatedBy, "OIDC:john@example.test", "the creator is recorded")
+	assert.Equal(t, strings.Contains(w.Body.String(), "<code>"+list[0].ID+"."), true, "the token is shown once")
+	assert.Equal(t, w.Header().Get("Cache-Control"), "no-store", "the token is not cached")
+
+	w = post("/admin/tokens/new/", url.Values{"name": {"backup"}, "scope": {ScopeRead}})
+	assert.Equal(t, w.Code, http.StatusConflict, "token names are unique")
+	w = post("/admin/tokens/new/", url.Values{"name": {"other"}, "scope": {"write"}})
+	assert.Equal(t, w.Code, http.StatusBadRequest, "unknown scopes are refused")
+
+	w = post("/admin/tokens/"+list[0].ID+"/revoke/", url.Values{})
+	assert.Equal(t, w.Code, http.StatusSeeOther, "the token is revoked")
+	assert.Equal(t, len(tokens.List()), 0, "no more token")
+
+	w = post("/admin/tokens/"+list[0].ID+"/revoke/", url.Values{})
+	assert.Equal(t, w.Code, http.StatusNotFound, "unknown tokens cannot be revoked")
+
+	entries := readAuditLog(t, audit)
+	assert.Equal(t, len(entries), 4, "the valid requests are audited")
+	assert.Equal(t, entries[0].Result, "done", "the token has been created")
+	assert.Equal(t, entries[1].Result, errDuplicateToken.Error()+": 'backup'", "the duplicate token has not been created")
+	assert.Equal(t, entries[2].Result, "done", "the token has been revoked")
+	assert.Equal(t, entries[3].Result, "unknown token", "the unknown token has not been revoked")
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+type AuditEntry struct {
+	Date    time.Time `yaml:"date"`
+	User    string    `yaml:"user"`
+	Action  string    `yaml:"action"`
+	Album   string    `yaml:"album,omitempty"`
+	Media   string    `yaml:"media,omitempty"`
+	Details string    `yaml:"details,omitempty"`
+	Result  string    `yaml:"result"` // "done" or the error
+}
+
+// AuditLog records the changes requested through the admin interface, once
+// made, with their outcome.
+// Entries are only appended, never rewritten.
+type AuditLog struct {
+	Path string
+
+	lock sync.Mutex
+}
+
+func InitAuditLog(path string) (*AuditLog, error) {
+	// Fail early if the file cannot be written
+	err := appendToFile(path, []byte{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditLog{Path: path}, nil
+}
+
+// auditResult returns the outcome of a change, as recorded in the audit log
+func auditResult(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	return "done"
+}
+
+func (audit *AuditLog) Record(entry AuditEntry) error {
+	if entry.Date.IsZero() {
+		entry.Date = time.Now()
+	}
+
+	yamlData, err := yaml.Marshal([1]AuditEntry{entry})
+	if err != nil {
+		return err
+	}
+
+	audit.lock.Lock()
+	defer audit.lock.Unlock()
+
+	return appendToFile(audit.Path, yamlData)
+}
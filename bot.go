@@ -13,20 +13,37 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
 
+// Time after which the message giving a new API token is deleted (the
+// TokenCreated message tells how long)
+const tokenMessageLifetime = 5 * time.Minute
+
 type TelegramBot struct {
 	MediaStore            *MediaStore
 	TokenGenerator        *TokenGenerator
 	GlobalTokenValidity   int
 	PerAlbumTokenValidity int
 
+	DigestEnabled          bool
+	DigestReplacesForwards bool
+	MemoriesEnabled        bool
+	MemoriesCount          int
+
 	WebPublicURL     string
 	ChatDB           *ChatDB
+	MessageDB        *MessageDB
+	Preferences      *PreferencesDB
+	APITokens        *APITokenStore
 	AuthorizedUsers  map[string]bool
+	Admins           map[string]bool
 	RetryDelay       time.Duration
 	NewUpdateTimeout int
 	API              *tgbotapi.BotAPI
 	Commands         TelegramCommands
-	Messages         TelegramMessages
+	Messages         *MessageCatalog
+
+	// Map language codes to command descriptions (lowercase command key => description)
+	CommandDescriptions map[string]map[string]string
+	DefaultLanguage     string
 }
 
 type TelegramCommands struct {
@@ -35,26 +52,61 @@ type TelegramCommands struct {
 	Info     string
 	Share    string
 	Browse   string
+	Digest   string
+	Memories string
+	Language string
+	Token    string
+	Search   string
 }
 
 type TelegramMessages struct {
-	Forbidden        string
-	Help             string
-	MissingAlbumName string
-	ServerError      string
-	AlbumCreated     string
-	DoNotUnderstand  string
-	Info             string
-	InfoNoAlbum      string
-	NoUsername       string
-	ThankYouMedia    string
-	SharedAlbum      string
-	SharedGlobal     string
+	Forbidden           string
+	Help                string
+	MissingAlbumName    string
+	ServerError         string
+	AlbumCreated        string
+	DoNotUnderstand     string
+	Info                string
+	InfoNoAlbum         string
+	NoUsername          string
+	ThankYouMedia       string
+	ThankYouLocation    string
+	SharedAlbum         string
+	SharedGlobal        string
+	Digest              string
+	DigestAlbum         string
+	DigestSubscribed    string
+	DigestStopped       string
+	Memories            string
+	MemoryMedia         string
+	MemoriesOptIn       string
+	MemoriesOptOut      string
+	Language            string
+	LanguageChanged     string
+	LanguageUnknown     string
+	TokenCreated        string
+	TokenNotCreated     string
+	TokenList           string
+	TokenListEmpty      string
+	TokenRevoked        string
+	TokenUnknown        string
+	TokenUsage          string
+	TagsUpdated         string
+	TagsNone            string
+	CommentAdded        string
+	CommentInvalid      string
+	CommentNotification string
+	SearchUsage         string
+	SearchNoResult      string
+	SearchResults       string
+	SearchMedia         string
 }
 
 func NewTelegramBot() *TelegramBot {
 	bot := TelegramBot{}
 	bot.AuthorizedUsers = make(map[string]bool)
+	bot.Admins = make(map[string]bool)
+	bot.CommandDescriptions = make(map[string]map[string]string)
 	return &bot
 }
 
@@ -96,30 +148,52 @@ func (bot *TelegramBot) ProcessUpdate(update tgbotapi.Update) {
 
 	text := update.Message.Text
 	username := update.Message.From.UserName
+	messages := bot.messagesFor(update.Message)
 
 	if username == "" {
-		bot.replyToCommandWithMessage(update.Message, bot.Messages.NoUsername)
+		bot.replyToCommandWithMessage(update.Message, messages.NoUsername)
 		return
 	}
 	if !bot.AuthorizedUsers[username] {
 		log.Printf("[%s] unauthorized user", username)
-		bot.replyToCommandWithMessage(update.Message, bot.Messages.Forbidden)
+		bot.replyToCommandWithMessage(update.Message, messages.Forbidden)
 		return
 	}
 
+	if code := update.Message.From.LanguageCode; code != "" && bot.Preferences.Get(username).LanguageCode != code {
+		// Remember the language of the user for the messages sent outside of a conversation
+		err := bot.Preferences.Update(username, func(p *UserPreferences) {
+			p.LanguageCode = code
+		})
+		if err != nil {
+			log.Printf("[%s] cannot update preferences: %s", username, err)
+		}
+	}
+
+	_, knownChat := bot.ChatDB.Lookup(username)
 	err := bot.ChatDB.UpdateWith(username, update.Message.Chat.ID)
 	if err != nil {
 		log.Printf("[%s] cannot update chat db: %s", username, err)
+	} else if !knownChat && bot.Admins[username] {
+		// Now that we know the chat of this admin, we can publish the admin commands
+		bot.publishAdminCommands(update.Message.Chat.ID)
 	}
 
 	if update.Message.ReplyToMessage != nil {
+		// Replies to the message of a media act on this media
+		reply := MessageRef{ChatID: update.Message.Chat.ID, MessageID: update.Message.ReplyToMessage.MessageID}
+		if mediaId, ok := bot.MessageDB.Lookup(reply); ok && text != "" {
+			bot.handleMediaReply(update.Message, mediaId)
+			return
+		}
+
 		// Only deal with forced replies (reply to bot's messages)
 		if update.Message.ReplyToMessage.From == nil || update.Message.ReplyToMessage.From.UserName != bot.API.Self.UserName {
 			return
 		}
 
 		if update.Message.ReplyToMessage.Text != "" {
-			if update.Message.ReplyToMessage.Text == bot.Messages.MissingAlbumName {
+			if bot.Messages.IsAny(update.Message.ReplyToMessage.Text, func(m TelegramMessages) string { return m.MissingAlbumName }) {
 				log.Printf("[%s] reply to previous command /%s: %s", username, bot.Commands.NewAlbum, text)
 				bot.handleNewAlbumCommandReply(update.Message)
 				return
@@ -130,7 +204,9 @@ func (bot *TelegramBot) ProcessUpdate(update tgbotapi.Update) {
 	if text != "" {
 		if update.Message.IsCommand() {
 			log.Printf("[%s] command: %s", username, text)
-			switch update.Message.Command() {
+			// Commands are lowercase in the Telegram clients but they may be
+			// configured or typed with uppercase letters
+			switch strings.ToLower(update.Message.Command()) {
 			case "start", bot.Commands.Help:
 				bot.handleHelpCommand(update.Message)
 			case bot.Commands.Share:
@@ -141,52 +217,93 @@ func (bot *TelegramBot) ProcessUpdate(update tgbotapi.Update) {
 				bot.handleNewAlbumCommand(update.Message)
 			case bot.Commands.Info:
 				bot.handleInfoCommand(update.Message)
+			case bot.Commands.Digest:
+				bot.handleDigestCommand(update.Message)
+			case bot.Commands.Memories:
+				bot.handleMemoriesCommand(update.Message)
+			case bot.Commands.Language:
+				bot.handleLanguageCommand(update.Message)
+			case bot.Commands.Token:
+				bot.handleTokenCommand(update.Message)
+			case bot.Commands.Search:
+				bot.handleSearchCommand(update.Message)
 			default:
-				bot.replyToCommandWithMessage(update.Message, bot.Messages.DoNotUnderstand)
+				bot.replyToCommandWithMessage(update.Message, messages.DoNotUnderstand)
 			}
 		} else {
-			bot.replyToCommandWithMessage(update.Message, bot.Messages.DoNotUnderstand)
+			bot.replyToCommandWithMessage(update.Message, messages.DoNotUnderstand)
 		}
 	} else if update.Message.Photo != nil {
-		err := bot.handlePhoto(update.Message)
+		mediaId, err := bot.handlePhoto(update.Message)
 		if err != nil {
 			log.Printf("[%s] cannot add photo to current album: %s", username, err)
-			bot.replyToCommandWithMessage(update.Message, bot.Messages.ServerError)
+			bot.replyToCommandWithMessage(update.Message, messages.ServerError)
 			return
 		}
-		bot.dispatchMessage(update.Message)
-		bot.replyWithMessage(update.Message, bot.Messages.ThankYouMedia)
+		bot.recordMessages(update.Message, mediaId, bot.dispatchMessage(update.Message))
+		bot.replyWithMessage(update.Message, messages.ThankYouMedia)
 	} else if update.Message.Video != nil {
-		err := bot.handleVideo(update.Message)
+		mediaId, err := bot.handleVideo(update.Message)
 		if err != nil {
 			log.Printf("[%s] cannot add video to current album: %s", username, err)
-			bot.replyToCommandWithMessage(update.Message, bot.Messages.ServerError)
+			bot.replyToCommandWithMessage(update.Message, messages.ServerError)
+			return
+		}
+		bot.recordMessages(update.Message, mediaId, bot.dispatchMessage(update.Message))
+		bot.replyWithMessage(update.Message, messages.ThankYouMedia)
+	} else if update.Message.Location != nil {
+		err := bot.handleLocation(update.Message)
+		if err != nil {
+			log.Printf("[%s] cannot add location to current album: %s", username, err)
+			bot.replyToCommandWithMessage(update.Message, messages.ServerError)
 			return
 		}
-		bot.dispatchMessage(update.Message)
-		bot.replyWithMessage(update.Message, bot.Messages.ThankYouMedia)
+		bot.replyWithMessage(update.Message, messages.ThankYouLocation)
 	} else {
 		log.Printf("[%s] cannot handle this type of message", username)
-		bot.replyToCommandWithMessage(update.Message, bot.Messages.DoNotUnderstand)
+		bot.replyToCommandWithMessage(update.Message, messages.DoNotUnderstand)
 	}
 }
 
-func (bot *TelegramBot) dispatchMessage(message *tgbotapi.Message) {
+// dispatchMessage forwards a message to the other users and returns the
+// forwarded messages
+func (bot *TelegramBot) dispatchMessage(message *tgbotapi.Message) []MessageRef {
+	var forwards []MessageRef
 	for user, _ := range bot.AuthorizedUsers {
 		if user != message.From.UserName {
-			if _, ok := bot.ChatDB.Db[user]; !ok {
+			chatId, ok := bot.ChatDB.Lookup(user)
+			if !ok {
 				log.Printf("[%s] The chat db does not have any mapping for %s, skipping...", message.From.UserName, user)
 				continue
 			}
 
-			msg := tgbotapi.NewForward(bot.ChatDB.Db[user], message.Chat.ID, message.MessageID)
+			if bot.DigestReplacesForwards && bot.Preferences.Get(user).Digest {
+				// This user will get the media in its next digest
+				continue
+			}
 
-			_, err := bot.API.Send(msg)
+			msg := tgbotapi.NewForward(chatId, message.Chat.ID, message.MessageID)
+
+			forward, err := bot.API.Send(msg)
 			if err != nil {
-				log.Printf("[%s] Cannot dispatch message to %s (chat id = %d)", message.From.UserName, user, bot.ChatDB.Db[user])
+				log.Printf("[%s] Cannot dispatch message to %s (chat id = %d)", message.From.UserName, user, chatId)
+				continue
 			}
+			forwards = append(forwards, MessageRef{ChatID: chatId, MessageID: forward.MessageID})
 		}
 	}
+
+	return forwards
+}
+
+// recordMessages remembers the messages a media has been sent or forwarded
+// in, so that users can reply to them
+func (bot *TelegramBot) recordMessages(message *tgbotapi.Message, mediaId string, forwards []MessageRef) {
+	refs := append([]MessageRef{{ChatID: message.Chat.ID, MessageID: message.MessageID}}, forwards...)
+	err := bot.MessageDB.Record(mediaId, refs...)
+	if err != nil {
+		log.Printf("[%s] cannot update message db: %s", message.From.UserName, err)
+	}
 }
 
 func (bot *TelegramBot) getFile(message *tgbotapi.Message, telegramFileId string, mediaStoreId string) error {
@@ -243,7 +360,7 @@ func (bot *TelegramBot) getFile(message *tgbotapi.Message, telegramFileId string
 	return nil
 }
 
-func (bot *TelegramBot) handlePhoto(message *tgbotapi.Message) error {
+func (bot *TelegramBot) handlePhoto(message *tgbotapi.Message) (string, error) {
 	// Find the best resolution among all available sizes
 	fileId := ""
 	maxWidth := 0
@@ -260,21 +377,22 @@ func (bot *TelegramBot) handlePhoto(message *tgbotapi.Message) error {
 	// Download the photo from the Telegram API and save it in the MediaStore
 	err := bot.getFile(message, fileId, mediaStoreId)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// parse the message timestamp
 	t := time.Unix(int64(message.Date), 0)
-	return bot.MediaStore.CommitPhoto(mediaStoreId, t, message.Caption)
+	return mediaStoreId, bot.MediaStore.CommitPhoto(mediaStoreId, t, message.Caption, bot.uploaderOf(message))
 }
-func (bot *TelegramBot) handleVideo(message *tgbotapi.Message) error {
+
+func (bot *TelegramBot) handleVideo(message *tgbotapi.Message) (string, error) {
 	// Get a unique id
 	mediaStoreId := bot.MediaStore.GetUniqueID()
 
 	// Download the video from the Telegram API and save it in the MediaStore
 	err := bot.getFile(message, message.Video.FileID, mediaStoreId)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// Download the video thumbnail from the Telegram API and save it in the MediaStore
@@ -285,29 +403,51 @@ func (bot *TelegramBot) handleVideo(message *tgbotapi.Message) error {
 
 	// parse the message timestamp
 	t := time.Unix(int64(message.Date), 0)
-	return bot.MediaStore.CommitVideo(mediaStoreId, t, message.Caption)
+	return mediaStoreId, bot.MediaStore.CommitVideo(mediaStoreId, t, message.Caption, bot.uploaderOf(message))
+}
+
+func (bot *TelegramBot) handleLocation(message *tgbotapi.Message) error {
+	point := TrackPoint{
+		Date: time.Unix(int64(message.Date), 0),
+		Location: Location{
+			Latitude:  message.Location.Latitude,
+			Longitude: message.Location.Longitude,
+		},
+	}
+
+	// Venues come with the location of the place
+	if message.Venue != nil {
+		point.Title = message.Venue.Title
+	}
+
+	return bot.MediaStore.AddTrackPoint(point)
+}
+
+// uploaderOf returns the identity of the Telegram user who sent a media, as
+// recorded in the MediaStore
+func (bot *TelegramBot) uploaderOf(message *tgbotapi.Message) string {
+	return WebUser{Username: message.From.UserName, Type: TypeTelegramUser}.String()
 }
 
 func (bot *TelegramBot) handleHelpCommand(message *tgbotapi.Message) {
-	bot.replyWithMessage(message, bot.Messages.Help)
+	bot.replyWithMessage(message, bot.messagesFor(message).Help)
 }
 
 func (bot *TelegramBot) handleShareCommand(message *tgbotapi.Message) {
+	messages := bot.messagesFor(message)
+
 	albumList, err := bot.MediaStore.ListAlbums()
 	if err != nil {
 		log.Printf("[%s] cannot get album list: %s", message.From.UserName, err)
-		bot.replyToCommandWithMessage(message, bot.Messages.ServerError)
+		bot.replyToCommandWithMessage(message, messages.ServerError)
 		return
 	}
 
 	var text strings.Builder
-	text.WriteString(fmt.Sprintf(bot.Messages.SharedAlbum, bot.PerAlbumTokenValidity))
+	text.WriteString(fmt.Sprintf(messages.SharedAlbum, bot.PerAlbumTokenValidity))
 	text.WriteString("\n")
 	sort.Sort(sort.Reverse(albumList))
-	var tokenData TokenData = TokenData{
-		Timestamp: time.Now(),
-		Username:  message.From.UserName,
-	}
+	now := time.Now()
 	for _, album := range albumList {
 		title := album.Title // TODO escape me
 		id := album.ID
@@ -315,58 +455,258 @@ func (bot *TelegramBot) handleShareCommand(message *tgbotapi.Message) {
 			id = "latest"
 			title = title + " 🔥"
 		}
-		tokenData.Entitlement = id
-		token := bot.TokenGenerator.NewToken(tokenData)
-		url := fmt.Sprintf("%s/s/%s/%s/album/%s/", bot.WebPublicURL, url.PathEscape(message.From.UserName), url.PathEscape(token), url.PathEscape(id))
+		url := bot.getShareURL(message.From.UserName, id, now)
 		text.WriteString(fmt.Sprintf("- [%s %s](%s)\n", album.Date.Format("2006-01"), title, url))
 	}
 
 	bot.replyWithMarkdownMessage(message, text.String())
 }
 
-func (bot *TelegramBot) handleBrowseCommand(message *tgbotapi.Message) {
+// getShareURL mints a new token for the given album and returns the sharing
+// link. An empty albumId means a global share of all albums.
+func (bot *TelegramBot) getShareURL(username string, albumId string, timestamp time.Time) string {
 	var tokenData TokenData = TokenData{
-		Timestamp: time.Now(),
-		Username:  message.From.UserName,
+		Timestamp:   timestamp,
+		Username:    username,
+		Entitlement: albumId,
 	}
 
-	// Global share
 	token := bot.TokenGenerator.NewToken(tokenData)
-	url := fmt.Sprintf("%s/s/%s/%s/album/", bot.WebPublicURL, url.PathEscape(message.From.UserName), url.PathEscape(token))
-	bot.replyWithMessage(message, fmt.Sprintf(bot.Messages.SharedGlobal, bot.GlobalTokenValidity))
+	link := fmt.Sprintf("%s/s/%s/%s/album/", bot.WebPublicURL, url.PathEscape(username), url.PathEscape(token))
+	if albumId != "" {
+		link += url.PathEscape(albumId) + "/"
+	}
+
+	return link
+}
+
+func (bot *TelegramBot) handleBrowseCommand(message *tgbotapi.Message) {
+	messages := bot.messagesFor(message)
+
+	// Global share
+	url := bot.getShareURL(message.From.UserName, "", time.Now())
+	bot.replyWithMessage(message, fmt.Sprintf(messages.SharedGlobal, bot.GlobalTokenValidity))
 	bot.replyWithMessage(message, url)
 }
 
 func (bot *TelegramBot) handleInfoCommand(message *tgbotapi.Message) {
+	messages := bot.messagesFor(message)
+
 	album, err := bot.MediaStore.GetCurrentAlbum()
 	if err != nil {
 		log.Printf("[%s] cannot get current album: %s", message.From.UserName, err)
-		bot.replyToCommandWithMessage(message, bot.Messages.ServerError)
+		bot.replyToCommandWithMessage(message, messages.ServerError)
 		return
 	}
 
 	if album.Title != "" {
-		bot.replyWithMessage(message, fmt.Sprintf(bot.Messages.Info, album.Title))
+		bot.replyWithMessage(message, fmt.Sprintf(messages.Info, album.Title))
 	} else {
-		bot.replyWithMessage(message, bot.Messages.InfoNoAlbum)
+		bot.replyWithMessage(message, messages.InfoNoAlbum)
+	}
+}
+
+func (bot *TelegramBot) handleDigestCommand(message *tgbotapi.Message) {
+	messages := bot.messagesFor(message)
+
+	if !bot.DigestEnabled {
+		bot.replyToCommandWithMessage(message, messages.DoNotUnderstand)
+		return
+	}
+
+	var subscribed bool
+	err := bot.Preferences.Update(message.From.UserName, func(p *UserPreferences) {
+		p.Digest = !p.Digest
+		if p.Digest {
+			// Only the media received from now on will be part of the digest
+			p.LastDigest = time.Now()
+		}
+		subscribed = p.Digest
+	})
+	if err != nil {
+		log.Printf("[%s] cannot update preferences: %s", message.From.UserName, err)
+		bot.replyToCommandWithMessage(message, messages.ServerError)
+		return
+	}
+
+	if subscribed {
+		bot.replyWithMessage(message, messages.DigestSubscribed)
+	} else {
+		bot.replyWithMessage(message, messages.DigestStopped)
+	}
+}
+
+func (bot *TelegramBot) handleMemoriesCommand(message *tgbotapi.Message) {
+	messages := bot.messagesFor(message)
+
+	if !bot.MemoriesEnabled {
+		bot.replyToCommandWithMessage(message, messages.DoNotUnderstand)
+		return
+	}
+
+	var optIn bool
+	err := bot.Preferences.Update(message.From.UserName, func(p *UserPreferences) {
+		p.Memories = !p.Memories
+		optIn = p.Memories
+	})
+	if err != nil {
+		log.Printf("[%s] cannot update preferences: %s", message.From.UserName, err)
+		bot.replyToCommandWithMessage(message, messages.ServerError)
+		return
+	}
+
+	if optIn {
+		bot.replyWithMessage(message, messages.MemoriesOptIn)
+	} else {
+		bot.replyWithMessage(message, messages.MemoriesOptOut)
+	}
+}
+
+func (bot *TelegramBot) handleLanguageCommand(message *tgbotapi.Message) {
+	messages := bot.messagesFor(message)
+	languages := strings.Join(bot.Messages.Languages(), ", ")
+
+	lang := strings.TrimSpace(message.CommandArguments())
+	if lang == "" {
+		current := bot.Messages.MatchCode(bot.languageOf(message.From.UserName, message.From.LanguageCode))
+		bot.replyWithMessage(message, fmt.Sprintf(messages.Language, current, languages))
+		return
+	}
+
+	if lang == "auto" {
+		// Use the language of the Telegram client
+		lang = ""
+	} else if !bot.Messages.IsSupported(lang) {
+		bot.replyToCommandWithMessage(message, fmt.Sprintf(messages.LanguageUnknown, languages))
+		return
+	}
+
+	err := bot.Preferences.Update(message.From.UserName, func(p *UserPreferences) {
+		p.Language = lang
+	})
+	if err != nil {
+		log.Printf("[%s] cannot update preferences: %s", message.From.UserName, err)
+		bot.replyToCommandWithMessage(message, messages.ServerError)
+		return
+	}
+
+	bot.replyWithMessage(message, bot.messagesFor(message).LanguageChanged)
+}
+
+func (bot *TelegramBot) handleTokenCommand(message *tgbotapi.Message) {
+	messages := bot.messagesFor(message)
+	username := message.From.UserName
+	if !bot.Admins[username] {
+		log.Printf("[%s] user is not an admin", username)
+		bot.replyToCommandWithMessage(message, messages.Forbidden)
+		return
+	}
+
+	args := strings.Fields(message.CommandArguments())
+	var action string
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch {
+	case action == "new" && len(args) >= 2:
+		scopes := args[2:]
+		if len(scopes) == 0 {
+			scopes = []string{ScopeRead}
+		}
+
+		_, token, err := bot.APITokens.Create(args[1], scopes, username)
+		if err != nil {
+			log.Printf("[%s] cannot create API token: %s", username, err)
+			bot.replyToCommandWithMessage(message, fmt.Sprintf(messages.TokenNotCreated, err))
+			return
+		}
+
+		log.Printf("[%s] created API token '%s' with scopes %s", username, args[1], strings.Join(scopes, ", "))
+		sent, err := bot.API.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(messages.TokenCreated, args[1], strings.Join(scopes, ", "), token)))
+		if err != nil {
+			log.Printf("[%s] cannot send the API token: %s", username, err)
+			return
+		}
+
+		// The token must not stay in the chat history
+		time.AfterFunc(tokenMessageLifetime, func() {
+			_, err := bot.API.DeleteMessage(tgbotapi.NewDeleteMessage(sent.Chat.ID, sent.MessageID))
+			if err != nil {
+				log.Printf("[%s] cannot delete the message of the API token: %s", username, err)
+			}
+		})
+	case action == "list":
+		tokens := bot.APITokens.List()
+		if len(tokens) == 0 {
+			bot.replyWithMessage(message, messages.TokenListEmpty)
+			return
+		}
+
+		var text strings.Builder
+		text.WriteString(messages.TokenList)
+		text.WriteString("\n")
+		for _, token := range tokens {
+			text.WriteString(fmt.Sprintf("- %s %s (%s), %s, %s\n", token.ID, token.Name, strings.Join(token.Scopes, ", "), token.CreatedBy, token.Created.Format("2006-01-02")))
+		}
+		bot.replyWithMessage(message, text.String())
+	case action == "revoke" && len(args) == 2:
+		ok, err := bot.APITokens.Revoke(args[1])
+		if err != nil {
+			log.Printf("[%s] cannot revoke API token: %s", username, err)
+			bot.replyToCommandWithMessage(message, messages.ServerError)
+			return
+		}
+
+		if !ok {
+			bot.replyToCommandWithMessage(message, messages.TokenUnknown)
+			return
+		}
+
+		log.Printf("[%s] revoked API token %s", username, args[1])
+		bot.replyWithMessage(message, messages.TokenRevoked)
+	default:
+		bot.replyToCommandWithMessage(message, messages.TokenUsage)
 	}
 }
 
 func (bot *TelegramBot) handleNewAlbumCommand(message *tgbotapi.Message) {
-	bot.replyWithForcedReply(message, bot.Messages.MissingAlbumName)
+	bot.replyWithForcedReply(message, bot.messagesFor(message).MissingAlbumName)
 }
 
 func (bot *TelegramBot) handleNewAlbumCommandReply(message *tgbotapi.Message) {
+	messages := bot.messagesFor(message)
 	albumName := message.Text
 
 	err := bot.MediaStore.NewAlbum(albumName)
 	if err != nil {
 		log.Printf("[%s] cannot create album '%s': %s", message.From.UserName, albumName, err)
-		bot.replyToCommandWithMessage(message, bot.Messages.ServerError)
+		bot.replyToCommandWithMessage(message, messages.ServerError)
 		return
 	}
 
-	bot.replyWithMessage(message, bot.Messages.AlbumCreated)
+	bot.replyWithMessage(message, messages.AlbumCreated)
+}
+
+// languageOf returns the language code of a user: the one chosen with the
+// language command if any, the one of its Telegram client otherwise.
+func (bot *TelegramBot) languageOf(username string, telegramLanguageCode string) string {
+	prefs := bot.Preferences.Get(username)
+	if prefs.Language != "" {
+		return prefs.Language
+	}
+	if telegramLanguageCode != "" {
+		return telegramLanguageCode
+	}
+	return prefs.LanguageCode
+}
+
+func (bot *TelegramBot) messagesFor(message *tgbotapi.Message) TelegramMessages {
+	return bot.Messages.Get(bot.languageOf(message.From.UserName, message.From.LanguageCode))
+}
+
+func (bot *TelegramBot) messagesForUser(username string) TelegramMessages {
+	return bot.Messages.Get(bot.languageOf(username, ""))
 }
 
 func (telegram *TelegramBot) replyToCommandWithMessage(message *tgbotapi.Message, text string) error {
@@ -389,6 +729,27 @@ func (telegram *TelegramBot) replyWithMarkdownMessage(message *tgbotapi.Message,
 	return err
 }
 
+// sendPhotoWithCaption sends the photo (or video thumbnail) of a media, or
+// only the caption if the media has no photo.
+func (telegram *TelegramBot) sendPhotoWithCaption(chatId int64, albumId string, files []string, caption string) error {
+	photo := findFileWithSuffix(files, ".jpeg")
+	if photo == "" {
+		_, err := telegram.API.Send(tgbotapi.NewMessage(chatId, caption))
+		return err
+	}
+
+	fd, _, err := telegram.MediaStore.OpenFile(albumId, photo)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	msg := tgbotapi.NewPhotoUpload(chatId, tgbotapi.FileReader{Name: photo, Reader: fd, Size: -1})
+	msg.Caption = caption
+	_, err = telegram.API.Send(msg)
+	return err
+}
+
 func (telegram *TelegramBot) replyWithForcedReply(message *tgbotapi.Message, text string) error {
 	msg := tgbotapi.NewMessage(message.Chat.ID, text)
 	msg.ReplyMarkup = tgbotapi.ForceReply{
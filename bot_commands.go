@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// A TelegramCommand is a command published to the Telegram clients
+// for autocompletion.
+type TelegramCommand struct {
+	Key       string // key of the command in the config (Telegram.Commands.<Key>)
+	Name      string
+	AdminOnly bool
+}
+
+// BotCommand is the JSON representation of a command in the Telegram Bot API
+type BotCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+type botCommandScope struct {
+	Type   string `json:"type"`
+	ChatID int64  `json:"chat_id,omitempty"`
+}
+
+func (bot *TelegramBot) availableCommands() []TelegramCommand {
+	commands := []TelegramCommand{
+		{Key: "Help", Name: bot.Commands.Help},
+		{Key: "NewAlbum", Name: bot.Commands.NewAlbum},
+		{Key: "Info", Name: bot.Commands.Info},
+		{Key: "Share", Name: bot.Commands.Share},
+		{Key: "Browse", Name: bot.Commands.Browse},
+		{Key: "Search", Name: bot.Commands.Search},
+		{Key: "Language", Name: bot.Commands.Language},
+	}
+
+	if bot.DigestEnabled {
+		commands = append(commands, TelegramCommand{Key: "Digest", Name: bot.Commands.Digest})
+	}
+
+	if bot.MemoriesEnabled {
+		commands = append(commands, TelegramCommand{Key: "Memories", Name: bot.Commands.Memories})
+	}
+
+	commands = append(commands, TelegramCommand{Key: "Token", Name: bot.Commands.Token, AdminOnly: true})
+
+	return commands
+}
+
+// commandList returns the commands available to regular users or admins,
+// described in the given language.
+func (bot *TelegramBot) commandList(lang string, admin bool) []BotCommand {
+	descriptions := bot.CommandDescriptions[lang]
+	var list []BotCommand
+	for _, command := range bot.availableCommands() {
+		if command.AdminOnly && !admin {
+			continue
+		}
+
+		description := descriptions[strings.ToLower(command.Key)]
+		if description == "" {
+			description = bot.CommandDescriptions[bot.DefaultLanguage][strings.ToLower(command.Key)]
+		}
+		if description == "" {
+			description = command.Name
+		}
+
+		list = append(list, BotCommand{Command: command.Name, Description: description})
+	}
+
+	return list
+}
+
+// PublishCommands registers the commands with Telegram, for all configured
+// languages. Regular users and admins get a different set of commands.
+func (bot *TelegramBot) PublishCommands() {
+	for lang := range bot.CommandDescriptions {
+		scope := botCommandScope{Type: "all_private_chats"}
+		err := bot.setMyCommands(bot.commandList(lang, false), scope, lang)
+		if err != nil {
+			log.Printf("Cannot publish the commands for language '%s': %s", lang, err)
+		}
+	}
+
+	// Users whose language is not configured get the default language
+	scope := botCommandScope{Type: "all_private_chats"}
+	err := bot.setMyCommands(bot.commandList(bot.DefaultLanguage, false), scope, "")
+	if err != nil {
+		log.Printf("Cannot publish the default commands: %s", err)
+	}
+
+	for admin := range bot.Admins {
+		if chatId, ok := bot.ChatDB.Lookup(admin); ok {
+			bot.publishAdminCommands(chatId)
+		}
+	}
+}
+
+// publishAdminCommands registers the admin commands in the private chat
+// of an admin.
+func (bot *TelegramBot) publishAdminCommands(chatId int64) {
+	scope := botCommandScope{Type: "chat", ChatID: chatId}
+	for lang := range bot.CommandDescriptions {
+		err := bot.setMyCommands(bot.commandList(lang, true), scope, lang)
+		if err != nil {
+			log.Printf("Cannot publish the admin commands for chat %d and language '%s': %s", chatId, lang, err)
+		}
+	}
+
+	err := bot.setMyCommands(bot.commandList(bot.DefaultLanguage, true), scope, "")
+	if err != nil {
+		log.Printf("Cannot publish the default admin commands for chat %d: %s", chatId, err)
+	}
+}
+
+func (bot *TelegramBot) setMyCommands(commands []BotCommand, scope botCommandScope, lang string) error {
+	commandsJson, err := json.Marshal(commands)
+	if err != nil {
+		return err
+	}
+
+	scopeJson, err := json.Marshal(scope)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("commands", string(commandsJson))
+	params.Set("scope", string(scopeJson))
+	if lang != "" {
+		params.Set("language_code", lang)
+	}
+
+	_, err = bot.API.MakeRequest("setMyCommands", params)
+	return err
+}
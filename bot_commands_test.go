@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestCommandList(t *testing.T) {
+	bot := NewTelegramBot()
+	bot.Commands = TelegramCommands{Help: "help", NewAlbum: "newalbum", Info: "info", Share: "share", Browse: "browse", Digest: "digest", Language: "language", Token: "token", Search: "search"}
+	bot.DefaultLanguage = "en"
+	bot.CommandDescriptions["en"] = map[string]string{"help": "Get some help", "info": "Get info"}
+	bot.CommandDescriptions["fr"] = map[string]string{"help": "Obtenir de l'aide"}
+
+	list := bot.commandList("fr", false)
+	assert.Equal(t, len(list), 7, "digest is not published when disabled")
+	assert.Equal(t, list[0], BotCommand{Command: "help", Description: "Obtenir de l'aide"}, "localized description")
+	assert.Equal(t, list[2], BotCommand{Command: "info", Description: "Get info"}, "fallback on the default language")
+	assert.Equal(t, list[1], BotCommand{Command: "newalbum", Description: "newalbum"}, "fallback on the command name")
+
+	bot.DigestEnabled = true
+	assert.Equal(t, len(bot.commandList("en", false)), 8, "digest is published when enabled")
+	assert.Equal(t, len(bot.commandList("en", true)), 9, "admins get the token command")
+}
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"sync"
 
 	"gopkg.in/yaml.v2"
 )
@@ -13,6 +14,8 @@ type ChatDB struct {
 
 	// Map usernames to chat id
 	Db map[string]int64
+
+	lock sync.RWMutex
 }
 
 func InitChatDB(path string) (*ChatDB, error) {
@@ -37,7 +40,18 @@ func InitChatDB(path string) (*ChatDB, error) {
 	return &ChatDB{Path: path, Db: db}, nil
 }
 
+func (chatdb *ChatDB) Lookup(username string) (int64, bool) {
+	chatdb.lock.RLock()
+	defer chatdb.lock.RUnlock()
+
+	chatId, ok := chatdb.Db[username]
+	return chatId, ok
+}
+
 func (chatdb *ChatDB) UpdateWith(username string, chatId int64) error {
+	chatdb.lock.Lock()
+	defer chatdb.lock.Unlock()
+
 	if _, ok := chatdb.Db[username]; !ok {
 		chatdb.Db[username] = chatId
 
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"gopkg.in/yaml.v2"
+)
+
+// Longest comment accepted, in characters
+const maxCommentLength = 1000
+
+var errInvalidComment = errors.New("Empty or too long comment")
+
+// A Comment on a media, posted from the web interface or from Telegram
+type Comment struct {
+	Media  string    `yaml:"media"`
+	Date   time.Time `yaml:"date"`
+	Author string    `yaml:"author"`         // display name, shown to all viewers
+	User   string    `yaml:"user,omitempty"` // identity of the author (WebUser.String()), never shown
+	Source string    `yaml:"source"`         // "web" or "telegram"
+	Text   string    `yaml:"text"`
+}
+
+// AddComment records a comment in the album of the media
+func (store *MediaStore) AddComment(comment Comment) error {
+	comment.Text = strings.TrimSpace(comment.Text)
+	if comment.Text == "" || utf8.RuneCountInString(comment.Text) > maxCommentLength {
+		return errInvalidComment
+	}
+
+	albumId, err := store.LocateMedia(comment.Media)
+	if err != nil {
+		return err
+	}
+
+	folder, err := store.albumFolder(albumId)
+	if err != nil {
+		return err
+	}
+
+	if comment.Date.IsZero() {
+		comment.Date = time.Now()
+	}
+
+	yamlData, err := yaml.Marshal([1]Comment{comment})
+	if err != nil {
+		return err
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	return appendToFile(filepath.Join(store.StoreLocation, folder, "comments.yaml"), yamlData)
+}
+
+// GetComments returns the comments on a media, oldest first
+func (store *MediaStore) GetComments(albumName string, mediaId string) ([]Comment, error) {
+	folder, err := store.albumFolder(albumName)
+	if err != nil {
+		return nil, err
+	}
+
+	yamlData, err := ioutil.ReadFile(filepath.Join(store.StoreLocation, folder, "comments.yaml"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var all []Comment
+	err = yaml.UnmarshalStrict(yamlData, &all)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []Comment
+	for _, comment := range all {
+		if comment.Media == mediaId {
+			comments = append(comments, comment)
+		}
+	}
+
+	return comments, nil
+}
+
+// deleteComments removes the comments on a media from the comments.yaml of
+// an album folder
+func (store *MediaStore) deleteComments(folder string, mediaId string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	filename := filepath.Join(store.StoreLocation, folder, "comments.yaml")
+	yamlData, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var all, kept []Comment
+	err = yaml.UnmarshalStrict(yamlData, &all)
+	if err != nil {
+		return err
+	}
+
+	for _, comment := range all {
+		if comment.Media != mediaId {
+			kept = append(kept, comment)
+		}
+	}
+	if len(kept) == len(all) {
+		return nil
+	}
+
+	yamlData, err = yaml.Marshal(kept)
+	if err != nil {
+		return err
+	}
+
+	return writeWithBackup(filename, yamlData)
+}
+
+// handlePostComment adds a comment from the media page
+func (web *WebInterface) handlePostComment(w http.ResponseWriter, r *http.Request, albumName string, mediaId string) {
+	user := GetWebUser(r)
+	if !user.CanInteract() {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	name := albumName
+	if name == "latest" {
+		name = ""
+	}
+
+	album, err := web.MediaStore.GetAlbum(name, false)
+	if errors.Is(err, ErrAlbumNotFound) {
+		web.handleFileNotFound(w, r)
+		return
+	} else if err != nil {
+		log.Printf("MediaStore.GetAlbum: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	if _, err := findMedia(album.Media, mediaId); err != nil {
+		web.handleFileNotFound(w, r)
+		return
+	}
+
+	err = web.MediaStore.AddComment(Comment{
+		Media:  mediaId,
+		Author: user.DisplayName(),
+		User:   user.String(),
+		Source: "web",
+		Text:   r.PostFormValue("text"),
+	})
+	if errors.Is(err, errInvalidComment) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Printf("MediaStore.AddComment: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	log.Printf("[%s] Commented media %s", user, mediaId)
+	http.Redirect(w, r, GetBasePath(r)+"/album/"+url.PathEscape(albumName)+"/media/"+url.PathEscape(mediaId)+"/#comments", http.StatusSeeOther)
+}
+
+// handleComment records the reply to the message of a media as a comment
+// and lets the uploader of the media know about it
+func (bot *TelegramBot) handleComment(message *tgbotapi.Message, mediaId string) {
+	messages := bot.messagesFor(message)
+	username := message.From.UserName
+
+	err := bot.MediaStore.AddComment(Comment{
+		Media:  mediaId,
+		Date:   time.Unix(int64(message.Date), 0),
+		Author: username,
+		User:   WebUser{Username: username, Type: TypeTelegramUser}.String(),
+		Source: "telegram",
+		Text:   message.Text,
+	})
+	if errors.Is(err, errInvalidComment) {
+		bot.replyToCommandWithMessage(message, messages.CommentInvalid)
+		return
+	} else if err != nil {
+		log.Printf("[%s] cannot comment media %s: %s", username, mediaId, err)
+		bot.replyToCommandWithMessage(message, messages.ServerError)
+		return
+	}
+
+	log.Printf("[%s] commented media %s", username, mediaId)
+	bot.replyToCommandWithMessage(message, messages.CommentAdded)
+	bot.notifyUploader(message, mediaId)
+}
+
+// notifyUploader sends a comment to the Telegram user who sent the media, in
+// reply to the message of this media in their chat
+func (bot *TelegramBot) notifyUploader(message *tgbotapi.Message, mediaId string) {
+	albumId, err := bot.MediaStore.LocateMedia(mediaId)
+	if err != nil {
+		log.Printf("[%s] cannot locate media %s: %s", message.From.UserName, mediaId, err)
+		return
+	}
+	media, err := bot.MediaStore.GetMedia(albumId, mediaId)
+	if err != nil || media == nil {
+		log.Printf("[%s] cannot get media %s: %s", message.From.UserName, mediaId, err)
+		return
+	}
+
+	prefix := TypeTelegramUser.String() + ":"
+	if !strings.HasPrefix(media.Uploader, prefix) {
+		return // Only Telegram users can be notified
+	}
+	uploader := strings.TrimPrefix(media.Uploader, prefix)
+	if uploader == message.From.UserName {
+		return
+	}
+
+	chatId, ok := bot.ChatDB.Lookup(uploader)
+	if !ok {
+		log.Printf("[%s] The chat db does not have any mapping for %s, skipping...", message.From.UserName, uploader)
+		return
+	}
+
+	text := fmt.Sprintf(bot.messagesForUser(uploader).CommentNotification, message.From.UserName, message.Text)
+	msg := tgbotapi.NewMessage(chatId, text)
+	if messageId, ok := bot.MessageDB.Find(chatId, mediaId); ok {
+		msg.ReplyToMessageID = messageId
+	}
+	_, err = bot.API.Send(msg)
+	if err != nil {
+		log.Printf("[%s] cannot notify %s of the comment: %s", message.From.UserName, uploader, err)
+	}
+}
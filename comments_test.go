@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestComments(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+
+	createTestAlbum(t, store, "Holidays")
+
+	id := addTestPhoto(t, store, time.Now(), "", "")
+
+	err := store.AddComment(Comment{Media: id, Author: "jane", Source: "telegram", Text: " So cute! "})
+	if err != nil {
+		t.Fatalf("AddComment(): error %s", err)
+	}
+
+	err = store.AddComment(Comment{Media: id, Author: "jane", Text: "   "})
+	assert.Equal(t, err, errInvalidComment, "empty comments are rejected")
+	err = store.AddComment(Comment{Media: id, Author: "jane", Text: strings.Repeat("a", maxCommentLength+1)})
+	assert.Equal(t, err, errInvalidComment, "long comments are rejected")
+	err = store.AddComment(Comment{Media: "unknown", Author: "jane", Text: "Hello"})
+	assert.Equal(t, err, ErrMediaNotFound, "comments are attached to existing media")
+
+	// Comments move with the album when it is closed
+	createTestAlbum(t, store, "Birthday")
+	albumId, err := store.LocateMedia(id)
+	if err != nil {
+		t.Fatalf("LocateMedia(): error %s", err)
+	}
+
+	web := &WebInterface{MediaStore: store, I18n: NewWebCatalog("en", map[string]I18n{"en": {}})}
+	user := &WebUser{Username: "john@example.test", Type: TypeOidcUser, Scopes: []string{ScopeRead}}
+	form := url.Values{"text": {"Where was it?"}}
+	r := httptest.NewRequest("POST", "/album/"+albumId+"/comment/"+id+"/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	web.ServeHTTP(w, withWebUser(r, user, ""))
+	assert.Equal(t, w.Code, http.StatusSeeOther, "the user is sent back to the media")
+
+	comments, err := store.GetComments(albumId, id)
+	if err != nil {
+		t.Fatalf("GetComments(): error %s", err)
+	}
+	assert.Equal(t, len(comments), 2, "both comments are there")
+	assert.Equal(t, comments[0].Text, "So cute!", "comments are trimmed")
+	assert.Equal(t, comments[1].Author, "john", "the email of the author is not shown")
+	assert.Equal(t, comments[1].User, "OIDC:john@example.test", "web comments are signed")
+	assert.Equal(t, comments[1].Source, "web", "web comments")
+
+	user = &WebUser{Username: "backup", Type: TypeAPIToken, Scopes: []string{ScopeRead}}
+	r = httptest.NewRequest("POST", "/album/"+albumId+"/comment/"+id+"/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	web.ServeHTTP(w, withWebUser(r, user, ""))
+	assert.Equal(t, w.Code, http.StatusForbidden, "API tokens cannot comment")
+}
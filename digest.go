@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// SendDigests sends to each subscribed user the list of albums that received
+// new media since their last digest.
+func (bot *TelegramBot) SendDigests(now time.Time) {
+	users := bot.Preferences.Users(func(p UserPreferences) bool {
+		return p.Digest
+	})
+
+	for _, username := range users {
+		err := bot.sendDigest(username, now)
+		if err != nil {
+			log.Printf("[%s] cannot send digest: %s", username, err)
+		}
+	}
+}
+
+func (bot *TelegramBot) sendDigest(username string, now time.Time) error {
+	chatId, ok := bot.ChatDB.Lookup(username)
+	if !ok {
+		return fmt.Errorf("The chat db does not have any mapping for %s", username)
+	}
+
+	messages := bot.messagesForUser(username)
+	since := bot.Preferences.Get(username).LastDigest
+	albums, err := bot.MediaStore.ListAlbumsUpdatedSince(since)
+	if err != nil {
+		return err
+	}
+
+	if len(albums) > 0 {
+		sort.Sort(sort.Reverse(albums))
+		msg := tgbotapi.NewMessage(chatId, fmt.Sprintf(messages.Digest, since.Format("2006-01-02")))
+		_, err = bot.API.Send(msg)
+		if err != nil {
+			return err
+		}
+
+		for _, album := range albums {
+			err = bot.sendDigestAlbum(chatId, username, album, now)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return bot.Preferences.Update(username, func(p *UserPreferences) {
+		p.LastDigest = now
+	})
+}
+
+func (bot *TelegramBot) sendDigestAlbum(chatId int64, username string, album Album, now time.Time) error {
+	id := album.ID
+	if id == "" {
+		id = "latest"
+	}
+	messages := bot.messagesForUser(username)
+	caption := fmt.Sprintf(messages.DigestAlbum, album.Title, len(album.Media), bot.getShareURL(username, id, now))
+
+	return bot.sendPhotoWithCaption(chatId, album.ID, album.CoverMedia.Files, caption)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// maxCaptionLength is the maximum number of characters of a caption used in
+// the name of a downloaded file
+const maxCaptionLength = 50
+
+// friendlyNames gives a human-friendly name (date + caption) to each file of
+// the media. Names are unique within the returned map, that associates the
+// files of the MediaStore to their friendly name.
+func friendlyNames(media []Media) map[string]string {
+	names := make(map[string]string)
+	used := make(map[string]bool)
+	for _, m := range media {
+		base := m.TakenDate().Format("2006-01-02 15.04.05")
+		if caption := cleanFileName(m.Caption); caption != "" {
+			base = base + " " + caption
+		}
+
+		// Media taken at the same time with the same caption get a counter.
+		// All files of a media (a video and its thumbnail) share the same name.
+		candidate := base
+		for i := 2; isUsed(used, candidate, m.Files); i++ {
+			candidate = fmt.Sprintf("%s (%d)", base, i)
+		}
+
+		for _, file := range m.Files {
+			name := candidate + filepath.Ext(file)
+			names[file] = name
+			used[name] = true
+		}
+	}
+
+	return names
+}
+
+func isUsed(used map[string]bool, base string, files []string) bool {
+	for _, file := range files {
+		if used[base+filepath.Ext(file)] {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanFileName removes the characters that are not allowed in file names
+// on the most common platforms and shortens the name if needed.
+func cleanFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.Join(strings.Fields(name), " ")
+
+	runes := []rune(name)
+	if len(runes) > maxCaptionLength {
+		name = strings.TrimSpace(string(runes[:maxCaptionLength]))
+	}
+
+	// Hidden files on Unix and trailing dots on Windows are confusing
+	return strings.Trim(name, ". ")
+}
+
+// writeManifest lists the files of the archive along with their captions
+func writeManifest(w io.Writer, album *Album, names map[string]string) error {
+	_, err := fmt.Fprintf(w, "%s\r\n%s\r\n\r\n", album.Title, album.Date.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+
+	for _, media := range album.Media {
+		for _, file := range media.Files {
+			caption := strings.Join(strings.Fields(media.Caption), " ")
+			_, err = fmt.Fprintf(w, "%s\t%s\r\n", names[file], caption)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeAlbumArchive streams the given media of an album as a ZIP archive.
+// Files are stored without compression since photos and videos are already
+// compressed.
+func (web *WebInterface) writeAlbumArchive(w io.Writer, album *Album, media []Media) error {
+	archive := zip.NewWriter(w)
+	names := friendlyNames(media)
+
+	for _, m := range media {
+		for _, file := range m.Files {
+			header := &zip.FileHeader{
+				Name:     names[file],
+				Method:   zip.Store,
+				Modified: m.TakenDate(),
+			}
+
+			out, err := archive.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+
+			err = web.copyFile(out, album.ID, file)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	manifest, err := archive.Create("manifest.txt")
+	if err != nil {
+		return err
+	}
+
+	selection := *album
+	selection.Media = media
+	err = writeManifest(manifest, &selection, names)
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func (web *WebInterface) copyFile(w io.Writer, albumName string, filename string) error {
+	fd, _, err := web.MediaStore.OpenFile(albumName, filename)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	_, err = io.Copy(w, fd)
+	return err
+}
+
+// archiveName returns the file name of the ZIP archive of an album
+func archiveName(album *Album) string {
+	name := album.ID
+	if name == "" && album.Title != "" {
+		// Same name as the folder of the album, once closed
+		name = album.Date.Format("2006-01-02") + "-" + sanitizeAlbumName(album.Title)
+	} else if name == "" {
+		name = "latest"
+	}
+	return name + ".zip"
+}
+
+func (web *WebInterface) sendAlbumArchive(w http.ResponseWriter, r *http.Request, album *Album, media []Media) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": archiveName(album)}))
+
+	// Once the archive has started, errors cannot be reported to the user
+	// anymore: the download will be truncated.
+	err := web.writeAlbumArchive(w, album, media)
+	if err != nil {
+		log.Printf("[%s] Cannot send the archive of album '%s': %s", GetWebUser(r), album.ID, err)
+	}
+}
+
+func (web *WebInterface) handleDownloadAlbum(w http.ResponseWriter, r *http.Request, albumName string) {
+	if albumName == "latest" {
+		albumName = ""
+	}
+
+	album, err := web.MediaStore.GetAlbum(albumName, false)
+	if errors.Is(err, ErrAlbumNotFound) {
+		web.handleFileNotFound(w, r)
+		return
+	} else if err != nil {
+		log.Printf("MediaStore.GetAlbum: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	web.sendAlbumArchive(w, r, album, album.Media)
+}
+
+// handleDownloadSelection streams the media chosen by the user. Only media of
+// the album in the URL can be selected, so that the entitlement checked by the
+// SecurityFrontend for this album applies.
+func (web *WebInterface) handleDownloadSelection(w http.ResponseWriter, r *http.Request, albumName string) {
+	user := GetWebUser(r)
+	if user.Type != TypeAnonymous && !user.HasScope(ScopeRead) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	if albumName == "latest" {
+		albumName = ""
+	}
+
+	album, err := web.MediaStore.GetAlbum(albumName, false)
+	if errors.Is(err, ErrAlbumNotFound) {
+		web.handleFileNotFound(w, r)
+		return
+	} else if err != nil {
+		log.Printf("MediaStore.GetAlbum: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	selected := make(map[string]bool)
+	for _, id := range r.PostForm["media"] {
+		selected[id] = true
+	}
+
+	// Keep the order of the album
+	media := make([]Media, 0, len(selected))
+	for _, m := range album.Media {
+		if selected[m.ID] {
+			media = append(media, m)
+			delete(selected, m.ID)
+		}
+	}
+
+	if len(selected) > 0 {
+		http.Error(w, "Unknown media", http.StatusBadRequest)
+		return
+	} else if len(media) == 0 {
+		http.Error(w, "No media selected", http.StatusBadRequest)
+		return
+	}
+
+	web.sendAlbumArchive(w, r, album, media)
+}
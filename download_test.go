@@ -0,0 +1,112 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestCleanFileName(t *testing.T) {
+	assert.Equal(t, cleanFileName("Happy birthday!"), "Happy birthday!", "regular captions are kept as-is")
+	assert.Equal(t, cleanFileName("  AC/DC:\n live  "), "AC DC live", "forbidden characters are removed")
+	assert.Equal(t, cleanFileName("...hidden"), "hidden", "leading dots are removed")
+	assert.Equal(t, len([]rune(cleanFileName(string(bytes.Repeat([]byte("é"), 100))))), maxCaptionLength, "long captions are shortened")
+}
+
+func TestFriendlyNames(t *testing.T) {
+	date := time.Date(2020, 5, 1, 14, 30, 0, 0, time.UTC)
+	media := []Media{
+		{ID: "1", Caption: "Wedding", Date: date, Files: []string{"1.jpeg"}},
+		{ID: "2", Caption: "Wedding", Date: date, Files: []string{"2.jpeg", "2.mp4"}},
+		{ID: "3", Date: date.Add(time.Minute), Files: []string{"3.jpeg"}},
+	}
+
+	names := friendlyNames(media)
+	assert.Equal(t, names["1.jpeg"], "2020-05-01 14.30.00 Wedding.jpeg", "date and caption")
+	assert.Equal(t, names["2.jpeg"], "2020-05-01 14.30.00 Wedding (2).jpeg", "duplicates get a counter")
+	assert.Equal(t, names["2.mp4"], "2020-05-01 14.30.00 Wedding (2).mp4", "files of the same media share the same name")
+	assert.Equal(t, names["3.jpeg"], "2020-05-01 14.31.00.jpeg", "media without caption")
+}
+
+func TestWriteAlbumArchive(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+
+	date := time.Date(2020, 5, 1, 14, 30, 0, 0, time.UTC)
+	addTestPhoto(t, store, date, "The cake", "")
+
+	album, err := store.GetAlbum("", false)
+	if err != nil {
+		t.Fatalf("GetAlbum(): error %s", err)
+	}
+
+	web := &WebInterface{MediaStore: store}
+	var buffer bytes.Buffer
+	err = web.writeAlbumArchive(&buffer, album, album.Media)
+	if err != nil {
+		t.Fatalf("writeAlbumArchive(): error %s", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader(): error %s", err)
+	}
+	assert.Equal(t, len(archive.File), 2, "the archive has the photo and the manifest")
+	assert.Equal(t, archive.File[0].Name, "2020-05-01 14.30.00 The cake.jpeg", "the photo has a friendly name")
+	assert.Equal(t, archive.File[1].Name, "manifest.txt", "the manifest is the last file")
+
+	manifest, err := archive.File[1].Open()
+	if err != nil {
+		t.Fatalf("zip.File.Open(): error %s", err)
+	}
+	defer manifest.Close()
+	content, _ := ioutil.ReadAll(manifest)
+	assert.Equal(t, bytes.Contains(content, []byte("2020-05-01 14.30.00 The cake.jpeg\tThe cake")), true, "the manifest lists the captions")
+}
+
+func TestDownloadSelection(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+
+	ids := make([]string, 3)
+	for i := range ids {
+		ids[i] = addTestPhoto(t, store, time.Now().Add(time.Duration(i)*time.Second), "", "")
+	}
+
+	web := &WebInterface{MediaStore: store, I18n: NewWebCatalog("en", map[string]I18n{"en": {}})}
+	user := &WebUser{Username: "john", Type: TypeTelegramUser, Scopes: []string{ScopeRead}}
+	download := func(ids ...string) *httptest.ResponseRecorder {
+		form := url.Values{"media": ids}
+		r := httptest.NewRequest("POST", "/album/latest/download/", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		web.ServeHTTP(w, withWebUser(r, user, "/s/john/token"))
+		return w
+	}
+
+	w := download(ids[2], ids[0])
+	assert.Equal(t, w.Code, http.StatusOK, "the selection is downloaded")
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader(): error %s", err)
+	}
+	assert.Equal(t, len(archive.File), 3, "the archive has the two selected photos and the manifest")
+
+	w = download(ids[0], "../../etc/passwd")
+	assert.Equal(t, w.Code, http.StatusBadRequest, "only media of the album can be selected")
+
+	w = download()
+	assert.Equal(t, w.Code, http.StatusBadRequest, "at least one media must be selected")
+}
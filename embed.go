@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Number of media shown by the embedded album widget
+const embedMediaCount = 8
+
+// Default size of the embedded album widget, in pixels
+const (
+	embedWidth  = 600
+	embedHeight = 400
+)
+
+// An oEmbedResponse describes the embedded album widget, see https://oembed.com/
+type oEmbedResponse struct {
+	Version      string `json:"version"`
+	Type         string `json:"type"`
+	Title        string `json:"title,omitempty"`
+	ProviderName string `json:"provider_name,omitempty"`
+	ProviderURL  string `json:"provider_url,omitempty"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// oEmbedTarget returns the path of the oEmbed endpoint of the album behind a
+// link to the web interface. Sharing links keep their token so that the
+// request is authorized as if the link itself was opened.
+func oEmbedTarget(link string, publicURL string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", false
+	}
+
+	public, err := url.Parse(publicURL)
+	if err != nil || u.Scheme != public.Scheme || u.Host != public.Host {
+		return "", false
+	}
+
+	var prefix string
+	head, tail := ShiftPath(strings.TrimPrefix(u.Path, strings.TrimSuffix(public.Path, "/")))
+	if head == "s" {
+		var username, token string
+		username, tail = ShiftPath(tail)
+		token, tail = ShiftPath(tail)
+		prefix = "/s/" + username + "/" + token
+		head, tail = ShiftPath(tail)
+	}
+
+	album, _ := ShiftPath(tail)
+	if head != "album" || album == "" {
+		return "", false
+	}
+
+	return prefix + "/album/" + album + "/oembed", true
+}
+
+// handleEmbedAlbum displays the latest media of an album in a compact widget,
+// to be framed by the configured origins
+func (web *WebInterface) handleEmbedAlbum(w http.ResponseWriter, r *http.Request, albumName string) {
+	if albumName == "latest" {
+		albumName = ""
+	}
+
+	album, err := web.MediaStore.GetAlbum(albumName, false)
+	if errors.Is(err, ErrAlbumNotFound) {
+		web.handleFileNotFound(w, r)
+		return
+	} else if err != nil {
+		log.Printf("MediaStore.GetAlbum: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	media := album.Media
+	if len(media) > embedMediaCount {
+		media = media[len(media)-embedMediaCount:]
+	}
+
+	w.Header().Set("Content-Security-Policy", web.Headers.ContentSecurityPolicy(web.FrameAncestors))
+	if len(web.FrameAncestors) > 0 {
+		w.Header().Del("X-Frame-Options")
+	}
+
+	err = web.EmbedTemplate.Execute(w, struct {
+		I18n  I18n
+		Album *Album
+		Media []Media
+	}{
+		web.negotiateLanguage(w, r),
+		album,
+		media,
+	})
+	if err != nil {
+		log.Printf("Template.Execute: %s", err)
+		web.handleError(w, r)
+		return
+	}
+}
+
+// handleOEmbed answers the oEmbed requests with the HTML code embedding the
+// album widget
+func (web *WebInterface) handleOEmbed(w http.ResponseWriter, r *http.Request, albumName string) {
+	query := r.URL.Query()
+	if format := query.Get("format"); format != "" && format != "json" {
+		http.Error(w, "Only the json format is supported", http.StatusNotImplemented)
+		return
+	}
+
+	width, height := embedWidth, embedHeight
+	if maxWidth, err := strconv.Atoi(query.Get("maxwidth")); err == nil && maxWidth > 0 && maxWidth < width {
+		width = maxWidth
+	}
+	if maxHeight, err := strconv.Atoi(query.Get("maxheight")); err == nil && maxHeight > 0 && maxHeight < height {
+		height = maxHeight
+	}
+
+	album, ok := web.getAPIAlbum(w, albumName)
+	if !ok {
+		return
+	}
+
+	id := album.ID
+	if id == "" {
+		id = "latest"
+	}
+
+	publicURL := strings.TrimSuffix(web.PublicURL, "/")
+	src := publicURL + GetBasePath(r) + "/album/" + url.PathEscape(id) + "/embed/"
+	i18n := web.I18n.Negotiate(r)
+	web.apiResponse(w, oEmbedResponse{
+		Version:      "1.0",
+		Type:         "rich",
+		Title:        album.Title,
+		ProviderName: i18n.SiteName,
+		ProviderURL:  publicURL + "/",
+		HTML: fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" title="%s" loading="lazy" style="border: 0"></iframe>`,
+			html.EscapeString(src), width, height, html.EscapeString(album.Title)),
+		Width:  width,
+		Height: height,
+	}, http.StatusOK)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestOEmbedTarget(t *testing.T) {
+	public := "https://photos.example.test"
+	target := func(link string) string {
+		p, ok := oEmbedTarget(link, public)
+		if !ok {
+			return "not found"
+		}
+		return p
+	}
+
+	assert.Equal(t, target("https://photos.example.test/s/john/abc/album/latest/"), "/s/john/abc/album/latest/oembed", "share link")
+	assert.Equal(t, target("https://photos.example.test/s/john/abc/album/2020-07-14-beach/media/1234/"), "/s/john/abc/album/2020-07-14-beach/oembed", "link to a media")
+	assert.Equal(t, target("https://photos.example.test/album/latest/"), "/album/latest/oembed", "link without token")
+	assert.Equal(t, target("https://photos.example.test/s/john/abc/album/"), "not found", "no album")
+	assert.Equal(t, target("https://evil.example.test/album/latest/"), "not found", "another site")
+	assert.Equal(t, target("http://photos.example.test/album/latest/"), "not found", "another scheme")
+
+	securityFrontend := &SecurityFrontend{
+		PublicURL: public,
+		store:     sessions.NewCookieStore([]byte("0123456789abcdef0123456789abcdef")),
+		Protected: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	}
+	r := httptest.NewRequest("GET", "/oembed?url="+url.QueryEscape(public+"/album/latest/"), nil)
+	w := httptest.NewRecorder()
+	securityFrontend.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusUnauthorized, "oEmbed consumers are not redirected to the login page")
+}
+
+func TestEmbed(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+
+	createTestAlbum(t, store, "Holidays")
+	for i := 0; i < embedMediaCount+2; i++ {
+		addTestPhoto(t, store, time.Now(), "", "")
+	}
+
+	embedTemplate, err := getTemplate(http.Dir("web"), "/embed.html.template", "embed")
+	if err != nil {
+		t.Fatalf("getTemplate(): error %s", err)
+	}
+
+	web := &WebInterface{
+		MediaStore:     store,
+		I18n:           NewWebCatalog("en", map[string]I18n{"en": {SiteName: "My album"}}),
+		PublicURL:      "https://photos.example.test/",
+		EmbedTemplate:  embedTemplate,
+		FrameAncestors: []string{"https://wiki.example.test"},
+	}
+	user := &WebUser{Username: "john", Type: TypeTelegramUser, Scopes: []string{ScopeRead}}
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		web.ServeHTTP(w, withWebUser(httptest.NewRequest("GET", path, nil), user, "/s/john/abc"))
+		return w
+	}
+
+	w := get("/album/latest/oembed?maxwidth=400")
+	assert.Equal(t, w.Code, http.StatusOK, "oEmbed response")
+	var response oEmbedResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("json.Unmarshal(): error %s", err)
+	}
+	assert.Equal(t, response.Type, "rich", "rich content")
+	assert.Equal(t, response.Title, "Holidays", "album title")
+	assert.Equal(t, response.Width, 400, "maxwidth is honored")
+	assert.Equal(t, response.Height, embedHeight, "default height")
+	assert.Equal(t, strings.Contains(response.HTML, `src="https://photos.example.test/s/john/abc/album/latest/embed/"`), true, "the widget keeps the share token")
+
+	w = get("/album/latest/oembed?format=xml")
+	assert.Equal(t, w.Code, http.StatusNotImplemented, "xml is not supported")
+
+	w = get("/album/latest/embed/")
+	assert.Equal(t, w.Code, http.StatusOK, "widget")
+	assert.Equal(t, strings.Count(w.Body.String(), "<img "), embedMediaCount, "the latest media are shown")
+	assert.Equal(t, strings.Contains(w.Header().Get("Content-Security-Policy"), "frame-ancestors https://wiki.example.test"), true, "framing is allowed from the wiki")
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	headers := SecurityHeaders{ImageSources: []string{MapSettings{TileURL: "https://{s}.tile.example.test/{z}/{x}/{y}.png"}.TileSource()}}
+	handler := headers.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/album/", nil))
+	policy := w.Header().Get("Content-Security-Policy")
+	assert.Equal(t, strings.Contains(policy, "img-src 'self' https://*.tile.example.test;"), true, "map tiles are allowed")
+	assert.Equal(t, strings.Contains(policy, "frame-ancestors 'none'"), true, "framing is denied by default")
+	assert.Equal(t, w.Header().Get("X-Frame-Options"), "DENY", "framing is denied by default")
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Exif holds the tags of the EXIF segment of a JPEG file.
+//
+// Only the few tags needed by the MediaStore are decoded, see
+// https://www.exif.org/Exif2-2.PDF for the specifications.
+type Exif struct {
+	order binary.ByteOrder
+	tiff  []byte
+	main  map[uint16]exifEntry // IFD0
+	exif  map[uint16]exifEntry // Exif SubIFD
+	gps   map[uint16]exifEntry // GPS Info IFD
+}
+
+type exifEntry struct {
+	format uint16
+	count  uint32
+	value  []byte
+}
+
+const (
+	exifTagDateTime          = 0x0132
+	exifTagExifIFDPointer    = 0x8769
+	exifTagDateTimeOriginal  = 0x9003
+	exifTagGPSInfoIFDPointer = 0x8825
+
+	// Tags of the GPS Info IFD
+	exifTagGPSLatitudeRef  = 0x0001
+	exifTagGPSLatitude     = 0x0002
+	exifTagGPSLongitudeRef = 0x0003
+	exifTagGPSLongitude    = 0x0004
+
+	exifFormatAscii    = 2
+	exifFormatRational = 5
+)
+
+var exifFormatSizes = map[uint16]uint32{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+func readExifFromFile(filename string) (*Exif, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	return readExif(fd)
+}
+
+// readExif scans the JPEG segments until it finds the APP1 (Exif) segment
+func readExif(r io.Reader) (*Exif, error) {
+	reader := bufio.NewReader(r)
+	var marker [2]byte
+	_, err := io.ReadFull(reader, marker[:])
+	if err != nil {
+		return nil, err
+	}
+	if marker[0] != 0xFF || marker[1] != 0xD8 {
+		return nil, fmt.Errorf("Not a JPEG file")
+	}
+
+	for {
+		_, err := io.ReadFull(reader, marker[:])
+		if err != nil {
+			return nil, err
+		}
+		if marker[0] != 0xFF {
+			return nil, fmt.Errorf("Invalid JPEG marker")
+		}
+		if marker[1] == 0xDA || marker[1] == 0xD9 { // Start of Scan or End of Image
+			return nil, fmt.Errorf("No EXIF data")
+		}
+
+		var length uint16
+		err = binary.Read(reader, binary.BigEndian, &length)
+		if err != nil {
+			return nil, err
+		}
+		if length < 2 {
+			return nil, fmt.Errorf("Invalid JPEG segment length")
+		}
+
+		segment := make([]byte, length-2)
+		_, err = io.ReadFull(reader, segment)
+		if err != nil {
+			return nil, err
+		}
+
+		if marker[1] == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return parseExif(segment[6:])
+		}
+	}
+}
+
+func parseExif(tiff []byte) (*Exif, error) {
+	if len(tiff) < 8 {
+		return nil, fmt.Errorf("EXIF segment too short")
+	}
+
+	var exif Exif
+	exif.tiff = tiff
+	switch string(tiff[0:2]) {
+	case "II":
+		exif.order = binary.LittleEndian
+	case "MM":
+		exif.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("Invalid TIFF byte order")
+	}
+
+	var err error
+	exif.main, err = exif.readIFD(exif.order.Uint32(tiff[4:8]))
+	if err != nil {
+		return nil, err
+	}
+
+	if offset, ok := exif.uint32Value(exif.main, exifTagExifIFDPointer); ok {
+		exif.exif, err = exif.readIFD(offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A broken GPS IFD is not fatal, the date is more important
+	if offset, ok := exif.uint32Value(exif.main, exifTagGPSInfoIFDPointer); ok {
+		exif.gps, _ = exif.readIFD(offset)
+	}
+
+	return &exif, nil
+}
+
+func (exif *Exif) readIFD(offset uint32) (map[uint16]exifEntry, error) {
+	tiff := exif.tiff
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return nil, fmt.Errorf("IFD offset out of bounds")
+	}
+
+	count := int(exif.order.Uint16(tiff[offset:]))
+	entries := make(map[uint16]exifEntry, count)
+	for i := 0; i < count; i++ {
+		start := uint64(offset) + 2 + uint64(i)*12
+		if start+12 > uint64(len(tiff)) {
+			return nil, fmt.Errorf("IFD entry out of bounds")
+		}
+		raw := tiff[start : start+12]
+
+		entry := exifEntry{
+			format: exif.order.Uint16(raw[2:4]),
+			count:  exif.order.Uint32(raw[4:8]),
+		}
+		size, ok := exifFormatSizes[entry.format]
+		if !ok {
+			continue
+		}
+
+		// Values of four bytes or less are stored in the entry itself
+		length := uint64(size) * uint64(entry.count)
+		if length <= 4 {
+			entry.value = raw[8 : 8+length]
+		} else {
+			valueOffset := uint64(exif.order.Uint32(raw[8:12]))
+			if valueOffset+length > uint64(len(tiff)) {
+				continue
+			}
+			entry.value = tiff[valueOffset : valueOffset+length]
+		}
+
+		entries[exif.order.Uint16(raw[0:2])] = entry
+	}
+
+	return entries, nil
+}
+
+func (exif *Exif) uint32Value(ifd map[uint16]exifEntry, tag uint16) (uint32, bool) {
+	entry, ok := ifd[tag]
+	if !ok {
+		return 0, false
+	}
+
+	switch {
+	case entry.format == 4 && len(entry.value) >= 4:
+		return exif.order.Uint32(entry.value), true
+	case entry.format == 3 && len(entry.value) >= 2:
+		return uint32(exif.order.Uint16(entry.value)), true
+	}
+
+	return 0, false
+}
+
+func (exif *Exif) stringValue(ifd map[uint16]exifEntry, tag uint16) (string, bool) {
+	entry, ok := ifd[tag]
+	if !ok || entry.format != exifFormatAscii {
+		return "", false
+	}
+
+	return strings.TrimRight(string(entry.value), "\x00 "), true
+}
+
+func (exif *Exif) rationalValues(ifd map[uint16]exifEntry, tag uint16) ([]float64, bool) {
+	entry, ok := ifd[tag]
+	if !ok || entry.format != exifFormatRational {
+		return nil, false
+	}
+
+	values := make([]float64, entry.count)
+	for i := range values {
+		numerator := exif.order.Uint32(entry.value[i*8:])
+		denominator := exif.order.Uint32(entry.value[i*8+4:])
+		if denominator == 0 {
+			return nil, false
+		}
+		values[i] = float64(numerator) / float64(denominator)
+	}
+
+	return values, true
+}
+
+// gpsCoordinate decodes a latitude or a longitude, stored as degrees, minutes
+// and seconds along with a reference (N/S or E/W).
+func (exif *Exif) gpsCoordinate(tag uint16, refTag uint16, negativeRef string) (float64, bool) {
+	values, ok := exif.rationalValues(exif.gps, tag)
+	if !ok || len(values) != 3 {
+		return 0, false
+	}
+
+	ref, ok := exif.stringValue(exif.gps, refTag)
+	if !ok {
+		return 0, false
+	}
+
+	coordinate := values[0] + values[1]/60 + values[2]/3600
+	if ref == negativeRef {
+		coordinate = -coordinate
+	}
+
+	return coordinate, true
+}
+
+// Location returns where the photo has been taken, if the camera recorded it
+func (exif *Exif) Location() (Location, bool) {
+	latitude, ok := exif.gpsCoordinate(exifTagGPSLatitude, exifTagGPSLatitudeRef, "S")
+	if !ok {
+		return Location{}, false
+	}
+
+	longitude, ok := exif.gpsCoordinate(exifTagGPSLongitude, exifTagGPSLongitudeRef, "W")
+	if !ok {
+		return Location{}, false
+	}
+
+	return Location{Latitude: latitude, Longitude: longitude}, true
+}
+
+// DateTaken returns the date the photo has been taken, in the local timezone
+// since EXIF dates do not carry any timezone information.
+func (exif *Exif) DateTaken() (time.Time, bool) {
+	value, ok := exif.stringValue(exif.exif, exifTagDateTimeOriginal)
+	if !ok {
+		value, ok = exif.stringValue(exif.main, exifTagDateTime)
+	}
+	if !ok {
+		return time.Time{}, false
+	}
+
+	t, err := time.ParseInLocation("2006:01:02 15:04:05", value, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
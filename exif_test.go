@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+type testExifEntry struct {
+	tag    uint16
+	format uint16
+	count  uint32
+	value  []byte
+}
+
+// buildTestJpeg assembles a JPEG file having an EXIF segment made of an IFD0
+// pointing to the given sub IFDs (tag => entries).
+func buildTestJpeg(main []testExifEntry, subIFDs map[uint16][]testExifEntry) []byte {
+	order := binary.LittleEndian
+	var tiff bytes.Buffer
+	tiff.WriteString("II")
+	binary.Write(&tiff, order, uint16(42))
+	binary.Write(&tiff, order, uint32(8))
+
+	// Sub IFDs are written after IFD0, compute their offsets first
+	ifdSize := func(entries []testExifEntry) uint32 {
+		size := uint32(2 + 12*len(entries) + 4)
+		for _, e := range entries {
+			if len(e.value) > 4 {
+				size += uint32(len(e.value))
+			}
+		}
+		return size
+	}
+	entries := append([]testExifEntry{}, main...)
+	for tag := range subIFDs {
+		entries = append(entries, testExifEntry{tag: tag, format: 4, count: 1, value: make([]byte, 4)})
+	}
+	offset := 8 + ifdSize(entries)
+	var subTags []uint16
+	for i := range entries {
+		if sub, ok := subIFDs[entries[i].tag]; ok && entries[i].format == 4 && len(entries[i].value) == 4 {
+			order.PutUint32(entries[i].value, offset)
+			offset += ifdSize(sub)
+			subTags = append(subTags, entries[i].tag)
+		}
+	}
+
+	writeIFD := func(entries []testExifEntry, start uint32) {
+		dataOffset := start + 2 + 12*uint32(len(entries)) + 4
+		var data bytes.Buffer
+		binary.Write(&tiff, order, uint16(len(entries)))
+		for _, e := range entries {
+			binary.Write(&tiff, order, e.tag)
+			binary.Write(&tiff, order, e.format)
+			binary.Write(&tiff, order, e.count)
+			if len(e.value) > 4 {
+				binary.Write(&tiff, order, dataOffset+uint32(data.Len()))
+				data.Write(e.value)
+			} else {
+				var v [4]byte
+				copy(v[:], e.value)
+				tiff.Write(v[:])
+			}
+		}
+		binary.Write(&tiff, order, uint32(0))
+		tiff.Write(data.Bytes())
+	}
+	writeIFD(entries, 8)
+	for _, tag := range subTags {
+		writeIFD(subIFDs[tag], uint32(tiff.Len()))
+	}
+
+	var jpeg bytes.Buffer
+	jpeg.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	binary.Write(&jpeg, binary.BigEndian, uint16(tiff.Len()+8))
+	jpeg.WriteString("Exif\x00\x00")
+	jpeg.Write(tiff.Bytes())
+	jpeg.Write([]byte{0xFF, 0xD9})
+	return jpeg.Bytes()
+}
+
+func asciiEntry(tag uint16, s string) testExifEntry {
+	return testExifEntry{tag: tag, format: 2, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+func TestExifDateTaken(t *testing.T) {
+	jpeg := buildTestJpeg([]testExifEntry{asciiEntry(exifTagDateTime, "2020:05:06 10:11:12")},
+		map[uint16][]testExifEntry{
+			exifTagExifIFDPointer: {asciiEntry(exifTagDateTimeOriginal, "2019:12:24 20:30:00")},
+		})
+
+	exif, err := readExif(bytes.NewReader(jpeg))
+	if err != nil {
+		t.Errorf("readExif(): %s", err)
+		return
+	}
+
+	date, ok := exif.DateTaken()
+	assert.Equal(t, ok, true, "photo has a date")
+	assert.Equal(t, date, time.Date(2019, 12, 24, 20, 30, 0, 0, time.Local), "DateTimeOriginal is preferred")
+}
+
+func TestExifNoData(t *testing.T) {
+	_, err := readExif(bytes.NewReader([]byte{0xFF, 0xD8, 0xFF, 0xD9}))
+	if err == nil {
+		t.Errorf("readExif(): no error on a JPEG without EXIF")
+	}
+
+	_, err = readExif(bytes.NewReader([]byte("JPEG File")))
+	if err == nil {
+		t.Errorf("readExif(): no error on an invalid file")
+	}
+}
+
+func rationalEntry(tag uint16, values ...uint32) testExifEntry {
+	value := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(value[4*i:], v)
+	}
+	return testExifEntry{tag: tag, format: exifFormatRational, count: uint32(len(values) / 2), value: value}
+}
+
+func TestExifLocation(t *testing.T) {
+	jpeg := buildTestJpeg(nil, map[uint16][]testExifEntry{
+		exifTagGPSInfoIFDPointer: {
+			asciiEntry(exifTagGPSLatitudeRef, "N"),
+			rationalEntry(exifTagGPSLatitude, 48, 1, 51, 1, 2400, 100),
+			asciiEntry(exifTagGPSLongitudeRef, "W"),
+			rationalEntry(exifTagGPSLongitude, 2, 1, 21, 1, 0, 1),
+		},
+	})
+
+	exif, err := readExif(bytes.NewReader(jpeg))
+	if err != nil {
+		t.Errorf("readExif(): %s", err)
+		return
+	}
+
+	location, ok := exif.Location()
+	assert.Equal(t, ok, true, "photo has a location")
+	assert.Equal(t, location.Latitude, 48+51.0/60+24.0/3600, "latitude is decoded from degrees, minutes and seconds")
+	assert.Equal(t, location.Longitude, -(2 + 21.0/60), "western longitudes are negative")
+
+	exif, _ = readExif(bytes.NewReader(buildTestJpeg([]testExifEntry{asciiEntry(exifTagDateTime, "2020:05:06 10:11:12")}, nil)))
+	_, ok = exif.Location()
+	assert.Equal(t, ok, false, "photo has no location")
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FavoritesDB stores the favorite media of each web user
+type FavoritesDB struct {
+	Path string
+
+	// Map user identities (see WebUser.String) to media ids
+	Db map[string][]string
+
+	// Changes on every update
+	version int
+
+	lock sync.RWMutex
+}
+
+func InitFavoritesDB(path string) (*FavoritesDB, error) {
+	db := make(map[string][]string)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	yamlData, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(yamlData, &db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FavoritesDB{Path: path, Db: db}, nil
+}
+
+// IsFavorite returns true if the user marked the media as favorite
+func (favorites *FavoritesDB) IsFavorite(user string, mediaId string) bool {
+	favorites.lock.RLock()
+	defer favorites.lock.RUnlock()
+
+	for _, id := range favorites.Db[user] {
+		if id == mediaId {
+			return true
+		}
+	}
+	return false
+}
+
+// Toggle marks or unmarks a media as favorite and returns its new state
+func (favorites *FavoritesDB) Toggle(user string, mediaId string) (bool, error) {
+	favorites.lock.Lock()
+	defer favorites.lock.Unlock()
+
+	var ids []string
+	favorite := true
+	for _, id := range favorites.Db[user] {
+		if id == mediaId {
+			favorite = false
+		} else {
+			ids = append(ids, id)
+		}
+	}
+	if favorite {
+		ids = append(ids, mediaId)
+	}
+
+	db := favorites.copyDb()
+	if len(ids) > 0 {
+		db[user] = ids
+	} else {
+		delete(db, user)
+	}
+
+	err := favorites.save(db)
+	if err != nil {
+		return false, err
+	}
+
+	return favorite, nil
+}
+
+// Forget removes a deleted media from the favorites of all users
+func (favorites *FavoritesDB) Forget(mediaId string) error {
+	favorites.lock.Lock()
+	defer favorites.lock.Unlock()
+
+	changed := false
+	db := favorites.copyDb()
+	for user, ids := range db {
+		var kept []string
+		for _, id := range ids {
+			if id != mediaId {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == len(ids) {
+			continue
+		}
+
+		changed = true
+		if len(kept) > 0 {
+			db[user] = kept
+		} else {
+			delete(db, user)
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	return favorites.save(db)
+}
+
+// copyDb returns a copy of the favorites that can be changed before being
+// saved. The lists of media ids are shared and must be replaced, not changed.
+func (favorites *FavoritesDB) copyDb() map[string][]string {
+	db := make(map[string][]string, len(favorites.Db))
+	for user, ids := range favorites.Db {
+		db[user] = ids
+	}
+	return db
+}
+
+// save writes the favorites to disk and only then replaces the current ones,
+// so that they are left untouched if they cannot be written.
+// The caller must hold the lock.
+func (favorites *FavoritesDB) save(db map[string][]string) error {
+	yamlData, err := yaml.Marshal(db)
+	if err != nil {
+		return err
+	}
+
+	err = writeWithBackup(favorites.Path, yamlData)
+	if err != nil {
+		return err
+	}
+
+	favorites.Db = db
+	favorites.version++
+	return nil
+}
+
+// Version returns a number changing each time the favorites are updated
+func (favorites *FavoritesDB) Version() int {
+	favorites.lock.RLock()
+	defer favorites.lock.RUnlock()
+
+	return favorites.version
+}
+
+// Counts returns the number of users having marked each media as favorite
+func (favorites *FavoritesDB) Counts() map[string]int {
+	favorites.lock.RLock()
+	defer favorites.lock.RUnlock()
+
+	counts := make(map[string]int)
+	for _, ids := range favorites.Db {
+		for _, id := range ids {
+			counts[id]++
+		}
+	}
+	return counts
+}
+
+// highlights returns the media marked as favorite by at least one user, the
+// most favorited first
+func highlights(media []Media, counts map[string]int) []Media {
+	var result []Media
+	for _, m := range media {
+		if counts[m.ID] > 0 {
+			result = append(result, m)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return counts[result[i].ID] > counts[result[j].ID]
+	})
+	return result
+}
+
+// filterAlbum keeps only the highlights of the album when requested with
+// the "highlights" query parameter, and returns true in that case
+func (web *WebInterface) filterAlbum(album *Album, r *http.Request) bool {
+	if r.URL.Query().Get("highlights") == "" || web.Favorites == nil {
+		return false
+	}
+
+	album.Media = highlights(album.Media, web.Favorites.Counts())
+	return true
+}
+
+type favoriteState struct {
+	Favorite bool // marked as favorite by the current user
+	Count    int  // by all users
+}
+
+type apiFavorite struct {
+	Favorite bool `json:"favorite"`
+	Count    int  `json:"count"`
+}
+
+// handleToggleFavorite marks or unmarks a media as favorite for the current
+// user
+func (web *WebInterface) handleToggleFavorite(w http.ResponseWriter, r *http.Request, albumName string, mediaId string) {
+	user := GetWebUser(r)
+	if !user.CanInteract() || web.Favorites == nil {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	name := albumName
+	if name == "latest" {
+		name = ""
+	}
+
+	album, err := web.MediaStore.GetAlbum(name, false)
+	if errors.Is(err, ErrAlbumNotFound) {
+		web.handleFileNotFound(w, r)
+		return
+	} else if err != nil {
+		log.Printf("MediaStore.GetAlbum: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	if _, err := findMedia(album.Media, mediaId); err != nil {
+		web.handleFileNotFound(w, r)
+		return
+	}
+
+	favorite, err := web.Favorites.Toggle(user.String(), mediaId)
+	if err != nil {
+		log.Printf("FavoritesDB.Toggle: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		web.apiResponse(w, apiFavorite{Favorite: favorite, Count: web.Favorites.Counts()[mediaId]}, http.StatusOK)
+		return
+	}
+
+	http.Redirect(w, r, GetBasePath(r)+"/album/"+url.PathEscape(albumName)+"/media/"+url.PathEscape(mediaId)+"/", http.StatusSeeOther)
+}
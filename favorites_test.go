@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestFavoritesDB(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	file := filepath.Join(tmp.RootDir, "favorites.yaml")
+	favorites, err := InitFavoritesDB(file)
+	if err != nil {
+		t.Fatalf("InitFavoritesDB(): %s", err)
+	}
+
+	favorite, err := favorites.Toggle("OIDC:john@example.test", "media-1")
+	if err != nil {
+		t.Fatalf("Toggle(): %s", err)
+	}
+	assert.Equal(t, favorite, true, "the media is now a favorite")
+
+	favorites.Toggle("Telegram:jane", "media-1")
+	favorites.Toggle("Telegram:jane", "media-2")
+	favorite, _ = favorites.Toggle("Telegram:jane", "media-2")
+	assert.Equal(t, favorite, false, "toggling again removes the favorite")
+
+	// Reload the db from the disk
+	favorites, err = InitFavoritesDB(file)
+	if err != nil {
+		t.Fatalf("InitFavoritesDB(): %s", err)
+	}
+	assert.Equal(t, favorites.IsFavorite("Telegram:jane", "media-1"), true, "favorites are persisted")
+	assert.Equal(t, favorites.IsFavorite("Telegram:jane", "media-2"), false, "removed favorites are persisted")
+	assert.Equal(t, favorites.Counts(), map[string]int{"media-1": 2}, "favorites are counted across users")
+
+	err = favorites.Forget("media-1")
+	if err != nil {
+		t.Fatalf("Forget(): %s", err)
+	}
+	favorites, err = InitFavoritesDB(file)
+	if err != nil {
+		t.Fatalf("InitFavoritesDB(): %s", err)
+	}
+	assert.Equal(t, favorites.Counts(), map[string]int{}, "deleted media are forgotten")
+
+	version := favorites.Version()
+	favorites.Path = filepath.Join(tmp.RootDir, "missing", "favorites.yaml")
+	_, err = favorites.Toggle("Telegram:jane", "media-3")
+	assert.Equal(t, err != nil, true, "the favorites cannot be written")
+	assert.Equal(t, favorites.IsFavorite("Telegram:jane", "media-3"), false, "the favorites are unchanged")
+	assert.Equal(t, favorites.Version(), version, "the version is unchanged")
+}
+
+func TestHighlights(t *testing.T) {
+	media := []Media{{ID: "1", Type: "photo"}, {ID: "2", Type: "photo"}, {ID: "3", Type: "video"}, {ID: "4", Type: "photo"}}
+	counts := map[string]int{"2": 1, "3": 5, "4": 2}
+
+	result := highlights(media, counts)
+	assert.Equal(t, len(result), 3, "only favorites are highlighted")
+	assert.Equal(t, result[0].ID, "3", "most favorited first")
+	assert.Equal(t, result[2].ID, "2", "least favorited last")
+
+	album := Album{Media: media}
+	album.setDefaultCover(counts)
+	assert.Equal(t, album.CoverMedia.ID, "4", "the most favorited photo is the cover")
+
+	album = Album{Media: media}
+	album.setDefaultCover(nil)
+	assert.Equal(t, album.CoverMedia.ID, "1", "the first photo is the cover without favorites")
+}
+
+func TestToggleFavorite(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+	favorites, err := InitFavoritesDB(filepath.Join(tmp.RootDir, "favorites.yaml"))
+	if err != nil {
+		t.Fatalf("InitFavoritesDB(): %s", err)
+	}
+
+	id := addTestPhoto(t, store, time.Now(), "", "")
+
+	web := &WebInterface{MediaStore: store, Favorites: favorites, I18n: NewWebCatalog("en", map[string]I18n{"en": {}})}
+	user := &WebUser{Username: "john", Type: TypeTelegramUser, Scopes: []string{ScopeRead}}
+	toggle := func(mediaId string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/album/latest/favorite/"+mediaId+"/", nil)
+		w := httptest.NewRecorder()
+		web.ServeHTTP(w, withWebUser(r, user, "/s/john/token"))
+		return w
+	}
+
+	w := toggle(id)
+	assert.Equal(t, w.Code, http.StatusSeeOther, "the user is sent back to the media")
+	assert.Equal(t, w.Header().Get("Location"), "/s/john/token/album/latest/media/"+id+"/", "the media page")
+	assert.Equal(t, favorites.IsFavorite(user.String(), id), true, "the media is a favorite of the user")
+
+	w = toggle("unknown")
+	assert.Equal(t, w.Code, http.StatusNotFound, "only media of the album can be favorited")
+
+	user = &WebUser{Username: "backup", Type: TypeAPIToken, Scopes: []string{ScopeRead}}
+	w = toggle(id)
+	assert.Equal(t, w.Code, http.StatusForbidden, "API tokens cannot mark favorites")
+	assert.Equal(t, favorites.IsFavorite(user.String(), id), false, "the favorites are unchanged")
+}
+
+func TestCachedCover(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+	favorites, err := InitFavoritesDB(filepath.Join(tmp.RootDir, "favorites.yaml"))
+	if err != nil {
+		t.Fatalf("InitFavoritesDB(): %s", err)
+	}
+	store.Favorites = favorites
+
+	createTestAlbum(t, store, "Holidays")
+	addTestPhoto(t, store, time.Now(), "", "")
+	createTestAlbum(t, store, "Birthday")
+	ids := []string{
+		addTestPhoto(t, store, time.Now(), "", ""),
+		addTestPhoto(t, store, time.Now(), "", ""),
+	}
+
+	album, err := store.getCachedAlbum(".current")
+	if err != nil {
+		t.Fatalf("getCachedAlbum(): error %s", err)
+	}
+	assert.Equal(t, album.CoverMedia.ID, ids[0], "the first photo is the cover without favorites")
+
+	_, err = store.Favorites.Toggle("Telegram:john", ids[1])
+	if err != nil {
+		t.Fatalf("Toggle(): error %s", err)
+	}
+	album, err = store.getCachedAlbum(".current")
+	if err != nil {
+		t.Fatalf("getCachedAlbum(): error %s", err)
+	}
+	assert.Equal(t, album.CoverMedia.ID, ids[1], "the cached album follows the favorites")
+
+	// The closed album is cached, deleted, then the cache misses
+	albums, err := store.ListAlbumsUpdatedSince(time.Time{})
+	if err != nil {
+		t.Fatalf("ListAlbumsUpdatedSince(): error %s", err)
+	}
+	var closed string
+	for _, album := range albums {
+		if album.ID != "" {
+			closed = album.ID
+		}
+	}
+	assert.Equal(t, closed != "", true, "the closed album is listed")
+	err = os.RemoveAll(filepath.Join(tmp.RootDir, closed))
+	if err != nil {
+		t.Fatalf("os.RemoveAll(): error %s", err)
+	}
+	addTestPhoto(t, store, time.Now(), "", "")
+	_, err = store.getCachedAlbum(".current")
+	if err != nil {
+		t.Fatalf("getCachedAlbum(): error %s", err)
+	}
+	_, ok := store.cache.entries[closed]
+	assert.Equal(t, ok, false, "deleted albums are evicted from the cache")
+}
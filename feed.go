@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"html"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated time.Time   `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Published time.Time   `xml:"published"`
+	Updated   time.Time   `xml:"updated"`
+	Author    *atomAuthor `xml:"author,omitempty"`
+	Links     []atomLink  `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// GetRecentMedia returns the media of all albums, the most recently added
+// first. At most limit media are returned.
+func (store *MediaStore) GetRecentMedia(limit int) ([]TimelineMedia, error) {
+	files, err := ioutil.ReadDir(store.StoreLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []TimelineMedia
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+
+		album, err := store.getCachedAlbum(file.Name())
+		if err != nil {
+			log.Printf("GetRecentMedia: Cannot extract album info for '%s'", file.Name())
+			continue
+		}
+
+		id := album.ID
+		if id == "" {
+			id = "latest"
+		}
+		for _, media := range album.Media {
+			all = append(all, TimelineMedia{Media: media, AlbumID: id})
+		}
+	}
+
+	return mostRecent(all, limit), nil
+}
+
+// mostRecent sorts media by date, the most recent first, and keeps at most
+// limit of them
+func mostRecent(all []TimelineMedia, limit int) []TimelineMedia {
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Date.After(all[j].Date)
+	})
+
+	if limit > 0 && len(all) > limit {
+		all = all[:limit]
+	}
+
+	return all
+}
+
+// newAtomFeed builds a feed from a list of media. URLs are absolute since
+// feed readers fetch them out of context: they start with the public URL
+// and the base path of the request, which carries the share token if any.
+func (web *WebInterface) newAtomFeed(r *http.Request, title string, selfPath string, alternatePath string, updated time.Time, all []TimelineMedia) atomFeed {
+	baseURL := strings.TrimSuffix(web.PublicURL, "/") + GetBasePath(r)
+	feed := atomFeed{
+		Xmlns: atomNamespace,
+		// The id must not change with the share token
+		ID:      strings.TrimSuffix(web.PublicURL, "/") + selfPath,
+		Title:   title,
+		Updated: updated,
+		Author:  atomAuthor{Name: web.I18n.Negotiate(r).SiteName},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: baseURL + selfPath},
+			{Rel: "alternate", Type: "text/html", Href: baseURL + alternatePath},
+		},
+		Entries: make([]atomEntry, 0, len(all)),
+	}
+
+	for _, media := range all {
+		albumURL := baseURL + "/album/" + url.PathEscape(media.AlbumID) + "/"
+		mediaURL := albumURL + "media/" + url.PathEscape(media.ID) + "/"
+		thumbnailURL := albumURL + "raw/" + url.PathEscape(findFileWithSuffix(media.Files, ".jpeg"))
+
+		entry := atomEntry{
+			ID:        "urn:uuid:" + media.ID,
+			Title:     media.Caption,
+			Published: media.Date,
+			Updated:   media.Date,
+			Links: []atomLink{
+				{Rel: "alternate", Type: "text/html", Href: mediaURL},
+				{Rel: "enclosure", Type: "image/jpeg", Href: thumbnailURL},
+			},
+			Content: atomContent{
+				Type: "html",
+				Body: `<p><a href="` + html.EscapeString(mediaURL) + `"><img src="` + html.EscapeString(thumbnailURL) + `" /></a></p>`,
+			},
+		}
+		if entry.Title == "" {
+			entry.Title = web.I18n.Negotiate(r).FormatDate(media.Date)
+		}
+		if media.Uploader != "" {
+			entry.Author = &atomAuthor{Name: uploaderName(media.Uploader)}
+		}
+		if media.Caption != "" {
+			entry.Content.Body += "<p>" + html.EscapeString(media.Caption) + "</p>"
+		}
+
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	if len(all) > 0 && all[0].Date.After(feed.Updated) {
+		feed.Updated = all[0].Date
+	} else if feed.Updated.IsZero() {
+		feed.Updated = time.Now()
+	}
+
+	return feed
+}
+
+func (web *WebInterface) serveAtomFeed(w http.ResponseWriter, r *http.Request, feed atomFeed) {
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	err := xml.NewEncoder(w).Encode(feed)
+	if err != nil {
+		log.Printf("xml.Encode: %s", err)
+	}
+}
+
+// handleAlbumFeed serves the feed of the media of an album
+func (web *WebInterface) handleAlbumFeed(w http.ResponseWriter, r *http.Request, albumName string) {
+	if albumName == "latest" {
+		albumName = ""
+	}
+
+	album, err := web.MediaStore.GetAlbum(albumName, false)
+	if errors.Is(err, ErrAlbumNotFound) {
+		web.handleFileNotFound(w, r)
+		return
+	} else if err != nil {
+		log.Printf("MediaStore.GetAlbum: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	id := album.ID
+	if id == "" {
+		id = "latest"
+	}
+
+	all := make([]TimelineMedia, len(album.Media))
+	for i, media := range album.Media {
+		all[i] = TimelineMedia{Media: media, AlbumID: id}
+	}
+
+	albumPath := "/album/" + url.PathEscape(id) + "/"
+	feed := web.newAtomFeed(r, album.Title, albumPath+"feed.atom", albumPath, album.Date, mostRecent(all, web.FeedSize))
+	web.serveAtomFeed(w, r, feed)
+}
+
+// handleGlobalFeed serves the feed of the latest media of all albums
+func (web *WebInterface) handleGlobalFeed(w http.ResponseWriter, r *http.Request) {
+	all, err := web.MediaStore.GetRecentMedia(web.FeedSize)
+	if err != nil {
+		log.Printf("MediaStore.GetRecentMedia: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	i18n := web.I18n.Negotiate(r)
+	feed := web.newAtomFeed(r, i18n.SiteName, "/album/feed.atom", "/album/", time.Time{}, all)
+	web.serveAtomFeed(w, r, feed)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestFeeds(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+
+	now := time.Now()
+	createTestAlbum(t, store, "Holidays")
+	addTestPhoto(t, store, now.Add(-3*time.Hour), "Beach", "")
+	addTestPhoto(t, store, now.Add(-2*time.Hour), "Boat", "")
+	createTestAlbum(t, store, "Birthday")
+	cake := addTestPhoto(t, store, now.Add(-time.Hour), "Cake", "OIDC:jane@example.test")
+
+	web := &WebInterface{
+		MediaStore: store,
+		I18n:       NewWebCatalog("en", map[string]I18n{"en": {SiteName: "My album"}}),
+		FeedSize:   2,
+		PublicURL:  "https://photos.example.test/",
+	}
+	user := &WebUser{Username: "john", Type: TypeTelegramUser, Scopes: []string{ScopeRead}}
+
+	get := func(path string) atomFeed {
+		r := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		web.ServeHTTP(w, withWebUser(r, user, "/s/john/token"))
+		assert.Equal(t, w.Code, http.StatusOK, "feed "+path)
+		assert.Equal(t, strings.HasPrefix(w.Header().Get("Content-Type"), "application/atom+xml"), true, "atom content type")
+
+		var feed atomFeed
+		err := xml.Unmarshal(w.Body.Bytes(), &feed)
+		if err != nil {
+			t.Fatalf("xml.Unmarshal: %s", err)
+		}
+		return feed
+	}
+
+	feed := get("/album/feed.atom")
+	assert.Equal(t, feed.Title, "My album", "global feed title")
+	assert.Equal(t, len(feed.Entries), 2, "the feed size is limited")
+	assert.Equal(t, feed.Entries[0].ID, "urn:uuid:"+cake, "the most recent media comes first")
+	assert.Equal(t, feed.Entries[1].Title, "Boat", "then the previous one")
+	assert.Equal(t, feed.Entries[0].Links[0].Href, "https://photos.example.test/s/john/token/album/latest/media/"+cake+"/", "links carry the share token")
+	assert.Equal(t, feed.Entries[0].Links[1].Rel, "enclosure", "thumbnail")
+	assert.Equal(t, feed.Entries[0].Links[1].Href, "https://photos.example.test/s/john/token/album/latest/raw/"+cake+".jpeg", "thumbnail URL")
+	assert.Equal(t, feed.Entries[0].Author.Name, "jane", "the email of the uploader is not published")
+	assert.Equal(t, feed.ID, "https://photos.example.test/album/feed.atom", "the feed id does not depend on the token")
+
+	albums, err := store.ListAlbums()
+	if err != nil {
+		t.Fatalf("ListAlbums(): error %s", err)
+	}
+	var holidays string
+	for _, album := range albums {
+		if album.Title == "Holidays" {
+			holidays = album.ID
+		}
+	}
+
+	feed = get("/album/" + holidays + "/feed.atom")
+	assert.Equal(t, feed.Title, "Holidays", "album feed title")
+	assert.Equal(t, len(feed.Entries), 2, "media of the album")
+	assert.Equal(t, feed.Entries[0].Title, "Boat", "the most recent media comes first")
+
+	feed = get("/album/latest/feed.atom")
+	assert.Equal(t, len(feed.Entries), 1, "media of the latest album")
+}
@@ -17,6 +17,8 @@ require (
 	github.com/spf13/afero v1.1.2
 	github.com/spf13/viper v1.6.3
 	github.com/technoweenie/multipartstreamer v1.0.1 // indirect
+	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2
+	golang.org/x/net v0.0.0-20190522155817-f3200d17e092
 	golang.org/x/oauth2 v0.0.0-20180821212333-d2e6202438be
 	golang.org/x/text v0.3.2
 	gopkg.in/square/go-jose.v2 v2.5.1 // indirect
@@ -22,7 +22,38 @@ func ShiftPath(p string) (head, tail string) {
 	return p[1:i], p[i:]
 }
 
-func ServeWebInterface(listenAddr string, webInterface http.Handler, staticFiles http.FileSystem) error {
+// SecurityHeaders are sent with every response. Pages cannot be framed,
+// unless they explicitly allow it (such as the embedded album widget).
+type SecurityHeaders struct {
+	ImageSources []string // where images can be loaded from, besides the web interface itself
+}
+
+// ContentSecurityPolicy returns the policy of the web interface: scripts and
+// styles are only loaded from the web interface, never inline.
+func (headers SecurityHeaders) ContentSecurityPolicy(frameAncestors []string) string {
+	ancestors := "'none'"
+	if len(frameAncestors) > 0 {
+		ancestors = strings.Join(frameAncestors, " ")
+	}
+
+	imageSources := append([]string{"'self'"}, headers.ImageSources...)
+	return "default-src 'self'; img-src " + strings.Join(imageSources, " ") +
+		"; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors " + ancestors
+}
+
+func (headers SecurityHeaders) Wrap(next http.Handler) http.Handler {
+	policy := headers.ContentSecurityPolicy(nil)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", policy)
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		// Share tokens are part of the URLs: they must not leak to other sites
+		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func ServeWebInterface(listenAddr string, webInterface http.Handler, staticFiles http.FileSystem, headers SecurityHeaders) error {
 	router := http.NewServeMux()
 	router.Handle("/js/", http.FileServer(staticFiles))
 	router.Handle("/css/", http.FileServer(staticFiles))
@@ -30,7 +61,7 @@ func ServeWebInterface(listenAddr string, webInterface http.Handler, staticFiles
 
 	server := &http.Server{
 		Addr:    listenAddr,
-		Handler: router,
+		Handler: headers.Wrap(router),
 	}
 	return server.ListenAndServe()
 }
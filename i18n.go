@@ -0,0 +1,191 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+const languageCookieName = "lang"
+
+// languageMatcher finds the best supported language for a user
+type languageMatcher struct {
+	// Supported languages, the default language being the first one
+	languages []string
+	matcher   language.Matcher
+}
+
+func newLanguageMatcher(defaultLanguage string, languages []string) languageMatcher {
+	supported := []string{defaultLanguage}
+	for _, lang := range languages {
+		if lang != defaultLanguage {
+			supported = append(supported, lang)
+		}
+	}
+	sort.Strings(supported[1:])
+
+	tags := make([]language.Tag, len(supported))
+	for i, lang := range supported {
+		tags[i] = language.Make(lang)
+	}
+
+	return languageMatcher{languages: supported, matcher: language.NewMatcher(tags)}
+}
+
+// Match returns the supported language that best matches the user's
+// preferred languages, the default language if none matches.
+func (m languageMatcher) Match(preferred ...language.Tag) string {
+	if len(preferred) == 0 {
+		return m.languages[0]
+	}
+
+	_, index, confidence := m.matcher.Match(preferred...)
+	if confidence == language.No {
+		return m.languages[0]
+	}
+
+	return m.languages[index]
+}
+
+// MatchCode is like Match for a single language code (such as "fr" or "en-US")
+func (m languageMatcher) MatchCode(code string) string {
+	if code == "" {
+		return m.languages[0]
+	}
+
+	tag, err := language.Parse(code)
+	if err != nil {
+		return m.languages[0]
+	}
+
+	return m.Match(tag)
+}
+
+// IsSupported returns true if the given language code is exactly one of the
+// supported languages
+func (m languageMatcher) IsSupported(code string) bool {
+	for _, lang := range m.languages {
+		if lang == code {
+			return true
+		}
+	}
+	return false
+}
+
+// MessageCatalog holds the Telegram messages, by language
+type MessageCatalog struct {
+	Messages map[string]TelegramMessages
+	languageMatcher
+}
+
+func NewMessageCatalog(defaultLanguage string, messages map[string]TelegramMessages) *MessageCatalog {
+	languages := make([]string, 0, len(messages))
+	for lang := range messages {
+		languages = append(languages, lang)
+	}
+
+	return &MessageCatalog{
+		Messages:        messages,
+		languageMatcher: newLanguageMatcher(defaultLanguage, languages),
+	}
+}
+
+// Get returns the messages in the language that best matches the given
+// language code
+func (catalog *MessageCatalog) Get(code string) TelegramMessages {
+	return catalog.Messages[catalog.MatchCode(code)]
+}
+
+// Languages returns the supported languages
+func (catalog *MessageCatalog) Languages() []string {
+	return catalog.languages
+}
+
+// IsAny returns true if the text is the given message in any language
+func (catalog *MessageCatalog) IsAny(text string, message func(TelegramMessages) string) bool {
+	for _, messages := range catalog.Messages {
+		if message(messages) == text {
+			return true
+		}
+	}
+	return false
+}
+
+// WebCatalog holds the web interface strings, by language
+type WebCatalog struct {
+	Translations map[string]I18n
+	languageMatcher
+}
+
+func NewWebCatalog(defaultLanguage string, translations map[string]I18n) *WebCatalog {
+	languages := make([]string, 0, len(translations))
+	for lang, i18n := range translations {
+		languages = append(languages, lang)
+		i18n.Lang = lang
+		translations[lang] = i18n
+	}
+
+	return &WebCatalog{
+		Translations:    translations,
+		languageMatcher: newLanguageMatcher(defaultLanguage, languages),
+	}
+}
+
+// Negotiate returns the strings in the language chosen by the user
+// (query parameter or cookie) or the one that best matches the
+// Accept-Language header.
+func (catalog *WebCatalog) Negotiate(r *http.Request) I18n {
+	if lang := r.URL.Query().Get(languageCookieName); catalog.IsSupported(lang) {
+		return catalog.Translations[lang]
+	}
+
+	cookie, err := r.Cookie(languageCookieName)
+	if err == nil && catalog.IsSupported(cookie.Value) {
+		return catalog.Translations[cookie.Value]
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil {
+		tags = nil
+	}
+
+	return catalog.Translations[catalog.Match(tags...)]
+}
+
+// All returns the strings of all supported languages, the default one first
+func (catalog *WebCatalog) All() []I18n {
+	all := make([]I18n, len(catalog.languages))
+	for i, lang := range catalog.languages {
+		all[i] = catalog.Translations[lang]
+	}
+	return all
+}
+
+// FormatDate formats a date using the DateFormat layout and the
+// localized month names
+func (i18n I18n) FormatDate(t time.Time) string {
+	layout := i18n.DateFormat
+	if layout == "" {
+		layout = "2006-01"
+	}
+
+	if len(i18n.Months) != 12 {
+		return t.Format(layout)
+	}
+
+	// Replace the month name by a placeholder that is kept as-is by Format
+	layout = strings.Replace(layout, "January", "\x00", -1)
+	return strings.Replace(t.Format(layout), "\x00", i18n.Months[t.Month()-1], -1)
+}
+
+// MonthName returns the localized name of a month
+func (i18n I18n) MonthName(month time.Month) string {
+	if len(i18n.Months) != 12 {
+		return month.String()
+	}
+
+	return i18n.Months[month-1]
+}
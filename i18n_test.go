@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestMessageCatalog(t *testing.T) {
+	catalog := NewMessageCatalog("en", map[string]TelegramMessages{
+		"en": {Help: "Hello", MissingAlbumName: "Which title?"},
+		"fr": {Help: "Bonjour", MissingAlbumName: "Quel titre ?"},
+	})
+
+	assert.Equal(t, catalog.Get("fr").Help, "Bonjour", "exact match")
+	assert.Equal(t, catalog.Get("fr-CA").Help, "Bonjour", "regional variant")
+	assert.Equal(t, catalog.Get("de").Help, "Hello", "unsupported language")
+	assert.Equal(t, catalog.Get("").Help, "Hello", "no language")
+	assert.Equal(t, catalog.Get("not a language!").Help, "Hello", "invalid language")
+	assert.Equal(t, catalog.Languages(), []string{"en", "fr"}, "default language first")
+	assert.Equal(t, catalog.IsSupported("fr"), true, "fr is supported")
+	assert.Equal(t, catalog.IsSupported("de"), false, "de is not supported")
+	assert.Equal(t, catalog.IsAny("Quel titre ?", func(m TelegramMessages) string { return m.MissingAlbumName }), true, "message in any language")
+}
+
+func TestWebCatalogNegotiate(t *testing.T) {
+	catalog := NewWebCatalog("en", map[string]I18n{
+		"en": {SiteName: "My photo album"},
+		"fr": {SiteName: "Mon album photo"},
+	})
+
+	r := httptest.NewRequest("GET", "/album/", nil)
+	assert.Equal(t, catalog.Negotiate(r).Lang, "en", "default language")
+
+	r.Header.Set("Accept-Language", "de-DE,fr;q=0.8,en;q=0.5")
+	assert.Equal(t, catalog.Negotiate(r).Lang, "fr", "Accept-Language")
+
+	r.AddCookie(&http.Cookie{Name: "lang", Value: "en"})
+	assert.Equal(t, catalog.Negotiate(r).Lang, "en", "cookie overrides Accept-Language")
+
+	r = httptest.NewRequest("GET", "/album/?lang=fr", nil)
+	r.AddCookie(&http.Cookie{Name: "lang", Value: "en"})
+	assert.Equal(t, catalog.Negotiate(r).SiteName, "Mon album photo", "query parameter overrides cookie")
+}
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2020, 8, 15, 0, 0, 0, 0, time.UTC)
+	i18n := I18n{DateFormat: "January 2006", Months: []string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"}}
+	assert.Equal(t, i18n.FormatDate(date), "août 2020", "localized month")
+	assert.Equal(t, I18n{DateFormat: "January 2006"}.FormatDate(date), "August 2020", "english month")
+	assert.Equal(t, I18n{}.FormatDate(date), "2020-08", "default format")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+type Location struct {
+	Latitude  float64 `yaml:"lat"`
+	Longitude float64 `yaml:"lon"`
+}
+
+// coordinates returns the location in the GeoJSON order (longitude first)
+func (location Location) coordinates() []float64 {
+	return []float64{location.Longitude, location.Latitude}
+}
+
+// A TrackPoint is a location shared in the Telegram chat during an album.
+// Venues (named places) have a title.
+type TrackPoint struct {
+	Date     time.Time `yaml:"date"`
+	Location Location  `yaml:",inline"`
+	Title    string    `yaml:"title,omitempty"`
+}
+
+// AddTrackPoint records a location in the current album and geotags the
+// media sent shortly before that have no location yet.
+func (store *MediaStore) AddTrackPoint(point TrackPoint) error {
+	yamlData, err := yaml.Marshal([1]TrackPoint{point})
+	if err != nil {
+		return err
+	}
+
+	store.lock.Lock()
+	err = appendToFile(filepath.Join(store.StoreLocation, ".current", "track.yaml"), yamlData)
+	store.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if store.GeotagWindow <= 0 {
+		return nil
+	}
+
+	return store.updateAlbumContent("", func(media []Media) ([]Media, error) {
+		for i := range media {
+			if media[i].Location == nil && isWithin(media[i].Date, point.Date, store.GeotagWindow) {
+				location := point.Location
+				media[i].Location = &location
+			}
+		}
+		return media, nil
+	})
+}
+
+// GetTrack returns the locations shared during an album, in chronological order
+func (store *MediaStore) GetTrack(albumName string) ([]TrackPoint, error) {
+	folder, err := store.albumFolder(albumName)
+	if err != nil {
+		return nil, err
+	}
+
+	yamlData, err := ioutil.ReadFile(filepath.Join(store.StoreLocation, folder, "track.yaml"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var track []TrackPoint
+	err = yaml.UnmarshalStrict(yamlData, &track)
+	if err != nil {
+		return nil, err
+	}
+
+	return track, nil
+}
+
+// geotag returns the location of the track point the closest in time to the
+// given date, if any within the window.
+func geotag(track []TrackPoint, date time.Time, window time.Duration) (Location, bool) {
+	var best *TrackPoint
+	for i := range track {
+		if !isWithin(track[i].Date, date, window) {
+			continue
+		}
+		if best == nil || absDuration(track[i].Date.Sub(date)) < absDuration(best.Date.Sub(date)) {
+			best = &track[i]
+		}
+	}
+
+	if best == nil {
+		return Location{}, false
+	}
+
+	return best.Location, true
+}
+
+func isWithin(a time.Time, b time.Time, window time.Duration) bool {
+	return absDuration(a.Sub(b)) <= window
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestListGeotaggedMedia(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+	id := addTestPhoto(t, store, time.Now(), "", "")
+	addTestPhoto(t, store, time.Now(), "", "")
+
+	// Photos sent through Telegram have no EXIF data: set the location by hand
+	err := store.updateAlbumContent("", func(media []Media) ([]Media, error) {
+		media[0].Location = &Location{Latitude: 48.8, Longitude: 2.3}
+		return media, nil
+	})
+	if err != nil {
+		t.Fatalf("updateAlbumContent(): error %s", err)
+	}
+
+	geotagged, err := store.ListGeotaggedMedia()
+	if err != nil {
+		t.Fatalf("ListGeotaggedMedia(): error %s", err)
+	}
+	assert.Equal(t, len(geotagged), 1, "the album has a geotagged media")
+	assert.Equal(t, len(geotagged[0].Media), 1, "only the geotagged media is listed")
+
+	collection := newGeoJSONFeatureCollection()
+	for _, album := range geotagged {
+		collection.addAlbum(album, "/s/john/token")
+	}
+	assert.Equal(t, len(collection.Features), 1, "the photo is on the map")
+	assert.Equal(t, collection.Features[0].Geometry.Coordinates, []float64{2.3, 48.8}, "GeoJSON coordinates are longitude first")
+	assert.Equal(t, collection.Features[0].Properties.(geoJSONProperties).URL, "/s/john/token/album/latest/media/"+id+"/", "the feature links to the media")
+}
+
+func TestGeotag(t *testing.T) {
+	now := time.Now()
+	track := []TrackPoint{
+		{Date: now.Add(-20 * time.Minute), Location: Location{Latitude: 1, Longitude: 1}},
+		{Date: now.Add(5 * time.Minute), Location: Location{Latitude: 2, Longitude: 2}},
+	}
+
+	location, ok := geotag(track, now, 30*time.Minute)
+	assert.Equal(t, ok, true, "a track point is within the window")
+	assert.Equal(t, location, Location{Latitude: 2, Longitude: 2}, "the closest track point is used")
+
+	_, ok = geotag(track, now.Add(-2*time.Hour), 30*time.Minute)
+	assert.Equal(t, ok, false, "no track point within the window")
+}
+
+func TestAddTrackPoint(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+	store.GeotagWindow = 30 * time.Minute
+
+	now := time.Now()
+	addTestPhoto(t, store, now, "", "")
+
+	point := TrackPoint{Date: now.Add(10 * time.Minute), Location: Location{Latitude: 48.8, Longitude: 2.3}}
+	err := store.AddTrackPoint(point)
+	if err != nil {
+		t.Fatalf("AddTrackPoint(): error %s", err)
+	}
+
+	track, err := store.GetTrack("")
+	if err != nil {
+		t.Fatalf("GetTrack(): error %s", err)
+	}
+	assert.Equal(t, len(track), 1, "the track point has been recorded")
+	assert.Equal(t, track[0].Location, point.Location, "the location is stored")
+
+	album, err := store.GetAlbum("", false)
+	if err != nil {
+		t.Fatalf("GetAlbum(): error %s", err)
+	}
+	assert.Equal(t, album.Media[0].Location != nil, true, "the photo has been geotagged")
+	assert.Equal(t, *album.Media[0].Location, point.Location, "the photo has the location of the track point")
+}
+
+func TestAddTrack(t *testing.T) {
+	now := time.Now()
+	track := []TrackPoint{
+		{Date: now, Location: Location{Latitude: 1, Longitude: 2}},
+		{Date: now.Add(time.Hour), Location: Location{Latitude: 3, Longitude: 4}, Title: "Eiffel Tower"},
+	}
+
+	collection := newGeoJSONFeatureCollection()
+	collection.addTrack(track)
+	assert.Equal(t, len(collection.Features), 2, "the route and the venue are in the collection")
+	assert.Equal(t, collection.Features[0].Properties, geoJSONPlaceProperties{Kind: "place", Title: "Eiffel Tower", Date: track[1].Date}, "venues are places")
+	assert.Equal(t, collection.Features[1].Geometry.Type, "LineString", "the route is a line")
+	assert.Equal(t, collection.Features[1].Geometry.Coordinates, [][]float64{{2, 1}, {4, 3}}, "the route follows the track points")
+
+	collection = newGeoJSONFeatureCollection()
+	collection.addTrack(nil)
+	assert.Equal(t, len(collection.Features), 0, "no route without track points")
+}
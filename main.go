@@ -9,6 +9,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	_ "github.com/nmasse-itix/Telegram-Photo-Album-Bot/statik"
@@ -32,6 +33,12 @@ func initConfig() {
 	To get the current album name, use "/info".
 	To share an album, use "/share album".
 	To share all albums, use "/share".
+	To search photos and videos, use "/search <words>".
+	To tag a photo or video, reply to it with "#tag".
+	To comment a photo or video, reply to it with your comment.
+	To get a digest of the new photos and videos, use "/digest".
+	To receive your memories of this day, use "/memories".
+	To change the language, use "/language".
 	If you are lost, you can get this message again with "/help".
 
 	Have a nice day!`)
@@ -43,8 +50,92 @@ func initConfig() {
 	viper.SetDefault("Telegram.Messages.InfoNoAlbum", "There is no album started, yet.")
 	viper.SetDefault("Telegram.Messages.NoUsername", "You need to set your Telegram username first!")
 	viper.SetDefault("Telegram.Messages.ThankYouMedia", "Got it, thanks!")
+	viper.SetDefault("Telegram.Messages.ThankYouLocation", "Got it, the photos sent around this time will be placed on the map.")
 	viper.SetDefault("Telegram.Messages.SharedAlbum", "Here are the albums and their sharing links. Links are valid for %d days.")
 	viper.SetDefault("Telegram.Messages.SharedGlobal", "All albums can be reached with the following link. Link is valid for %d days.")
+	viper.SetDefault("Telegram.Messages.Digest", "Here is what's new since %s.")
+	viper.SetDefault("Telegram.Messages.DigestAlbum", "%s: %d new photos and videos\n%s")
+	viper.SetDefault("Telegram.Messages.DigestSubscribed", "You will now receive a digest of the new photos and videos.")
+	viper.SetDefault("Telegram.Messages.DigestStopped", "You will no longer receive the digest.")
+	viper.SetDefault("Telegram.Messages.Memories", "On this day, in earlier years...")
+	viper.SetDefault("Telegram.Messages.MemoryMedia", "%s %s\n%s")
+	viper.SetDefault("Telegram.Messages.MemoriesOptIn", "You will now receive your memories of the day.")
+	viper.SetDefault("Telegram.Messages.MemoriesOptOut", "You will no longer receive your memories of the day.")
+	viper.SetDefault("Telegram.Messages.Language", "Current language is %s. Available languages: %s.\nUse \"/language <code>\" to change it or \"/language auto\" to use the language of your Telegram app.")
+	viper.SetDefault("Telegram.Messages.LanguageChanged", "Language changed.")
+	viper.SetDefault("Telegram.Messages.LanguageUnknown", "Unknown language. Available languages: %s.")
+	viper.SetDefault("Telegram.Messages.TokenCreated", "API token '%s' created with scopes %s. Keep it secret, this message will be deleted in 5 minutes:\n%s")
+	viper.SetDefault("Telegram.Messages.TokenNotCreated", "Cannot create the API token: %s")
+	viper.SetDefault("Telegram.Messages.TokenList", "Here are the API tokens:")
+	viper.SetDefault("Telegram.Messages.TokenListEmpty", "There is no API token.")
+	viper.SetDefault("Telegram.Messages.TokenRevoked", "API token revoked.")
+	viper.SetDefault("Telegram.Messages.TokenUnknown", "Unknown API token.")
+	viper.SetDefault("Telegram.Messages.TokenUsage", "Usage:\n/token new <name> [read] [upload] [admin]\n/token list\n/token revoke <id>")
+	viper.SetDefault("Telegram.Messages.TagsUpdated", "Tags of this media: %s")
+	viper.SetDefault("Telegram.Messages.TagsNone", "This media has no tags anymore.")
+	viper.SetDefault("Telegram.Messages.CommentAdded", "Comment added.")
+	viper.SetDefault("Telegram.Messages.CommentInvalid", "Sorry, this comment is empty or too long.")
+	viper.SetDefault("Telegram.Messages.CommentNotification", "%s commented on your photo: %s")
+	viper.SetDefault("Telegram.Messages.SearchUsage", "Usage: /search <words>")
+	viper.SetDefault("Telegram.Messages.SearchNoResult", "Sorry, I could not find any photo or video.")
+	viper.SetDefault("Telegram.Messages.SearchResults", "%d photos and videos found, here are the first ones. See them all at %s")
+	viper.SetDefault("Telegram.Messages.SearchMedia", "%s\n%s")
+
+	// Telegram messages, translated in French
+	viper.SetDefault("Telegram.Translations.fr.Forbidden", "Accès refusé")
+	viper.SetDefault("Telegram.Translations.fr.Help", `Bonjour, je suis le bot photo !
+
+	Vous pouvez m'envoyer vos photos et vidéos.
+
+	Pour commencer un album, utilisez "/newAlbum".
+	Pour connaître le nom de l'album en cours, utilisez "/info".
+	Pour partager un album, utilisez "/share album".
+	Pour partager tous les albums, utilisez "/share".
+	Pour rechercher des photos et vidéos, utilisez "/search <mots>".
+	Pour ajouter un mot-clé à une photo ou vidéo, répondez-lui avec "#mot".
+	Pour commenter une photo ou vidéo, répondez-lui avec votre commentaire.
+	Pour recevoir un résumé des nouvelles photos et vidéos, utilisez "/digest".
+	Pour recevoir vos souvenirs du jour, utilisez "/memories".
+	Pour changer de langue, utilisez "/language".
+	Si vous êtes perdu, vous pouvez obtenir ce message à nouveau avec "/help".
+
+	Bonne journée !`)
+	viper.SetDefault("Telegram.Translations.fr.MissingAlbumName", "Quel titre dois-je donner au nouvel album ?")
+	viper.SetDefault("Telegram.Translations.fr.ServerError", "Erreur interne du serveur")
+	viper.SetDefault("Telegram.Translations.fr.AlbumCreated", "Album créé")
+	viper.SetDefault("Telegram.Translations.fr.DoNotUnderstand", "Désolé, je n'ai pas compris votre demande.")
+	viper.SetDefault("Telegram.Translations.fr.Info", "L'album en cours s'appelle %s. Envoyez-moi vos photos et vidéos !")
+	viper.SetDefault("Telegram.Translations.fr.InfoNoAlbum", "Aucun album n'a encore été commencé.")
+	viper.SetDefault("Telegram.Translations.fr.NoUsername", "Vous devez d'abord définir votre nom d'utilisateur Telegram !")
+	viper.SetDefault("Telegram.Translations.fr.ThankYouMedia", "Bien reçu, merci !")
+	viper.SetDefault("Telegram.Translations.fr.ThankYouLocation", "Bien reçu, les photos envoyées vers cette heure-ci seront placées sur la carte.")
+	viper.SetDefault("Telegram.Translations.fr.SharedAlbum", "Voici les albums et leurs liens de partage. Les liens sont valables %d jours.")
+	viper.SetDefault("Telegram.Translations.fr.SharedGlobal", "Tous les albums sont accessibles avec le lien suivant. Le lien est valable %d jours.")
+	viper.SetDefault("Telegram.Translations.fr.Digest", "Voici les nouveautés depuis le %s.")
+	viper.SetDefault("Telegram.Translations.fr.DigestAlbum", "%s : %d nouvelles photos et vidéos\n%s")
+	viper.SetDefault("Telegram.Translations.fr.DigestSubscribed", "Vous recevrez désormais un résumé des nouvelles photos et vidéos.")
+	viper.SetDefault("Telegram.Translations.fr.DigestStopped", "Vous ne recevrez plus le résumé.")
+	viper.SetDefault("Telegram.Translations.fr.Memories", "Ce jour-là, les années précédentes...")
+	viper.SetDefault("Telegram.Translations.fr.MemoriesOptIn", "Vous recevrez désormais vos souvenirs du jour.")
+	viper.SetDefault("Telegram.Translations.fr.MemoriesOptOut", "Vous ne recevrez plus vos souvenirs du jour.")
+	viper.SetDefault("Telegram.Translations.fr.Language", "La langue actuelle est %s. Langues disponibles : %s.\nUtilisez \"/language <code>\" pour la changer ou \"/language auto\" pour utiliser la langue de votre application Telegram.")
+	viper.SetDefault("Telegram.Translations.fr.LanguageChanged", "Langue modifiée.")
+	viper.SetDefault("Telegram.Translations.fr.LanguageUnknown", "Langue inconnue. Langues disponibles : %s.")
+	viper.SetDefault("Telegram.Translations.fr.TokenCreated", "Jeton d'API '%s' créé avec les droits %s. Gardez-le secret, ce message sera supprimé dans 5 minutes :\n%s")
+	viper.SetDefault("Telegram.Translations.fr.TokenNotCreated", "Impossible de créer le jeton d'API : %s")
+	viper.SetDefault("Telegram.Translations.fr.TokenList", "Voici les jetons d'API :")
+	viper.SetDefault("Telegram.Translations.fr.TokenListEmpty", "Il n'y a aucun jeton d'API.")
+	viper.SetDefault("Telegram.Translations.fr.TokenRevoked", "Jeton d'API révoqué.")
+	viper.SetDefault("Telegram.Translations.fr.TokenUnknown", "Jeton d'API inconnu.")
+	viper.SetDefault("Telegram.Translations.fr.TokenUsage", "Utilisation :\n/token new <nom> [read] [upload] [admin]\n/token list\n/token revoke <id>")
+	viper.SetDefault("Telegram.Translations.fr.TagsUpdated", "Mots-clés de ce média : %s")
+	viper.SetDefault("Telegram.Translations.fr.TagsNone", "Ce média n'a plus de mots-clés.")
+	viper.SetDefault("Telegram.Translations.fr.CommentAdded", "Commentaire ajouté.")
+	viper.SetDefault("Telegram.Translations.fr.CommentInvalid", "Désolé, ce commentaire est vide ou trop long.")
+	viper.SetDefault("Telegram.Translations.fr.CommentNotification", "%s a commenté votre photo : %s")
+	viper.SetDefault("Telegram.Translations.fr.SearchUsage", "Utilisation : /search <mots>")
+	viper.SetDefault("Telegram.Translations.fr.SearchNoResult", "Désolé, je n'ai trouvé aucune photo ni vidéo.")
+	viper.SetDefault("Telegram.Translations.fr.SearchResults", "%d photos et vidéos trouvées, voici les premières. Retrouvez-les toutes sur %s")
 
 	// Telegram Commands
 	viper.SetDefault("Telegram.Commands.Help", "help")
@@ -52,6 +143,46 @@ func initConfig() {
 	viper.SetDefault("Telegram.Commands.NewAlbum", "newAlbum")
 	viper.SetDefault("Telegram.Commands.Share", "share")
 	viper.SetDefault("Telegram.Commands.Browse", "browse")
+	viper.SetDefault("Telegram.Commands.Digest", "digest")
+	viper.SetDefault("Telegram.Commands.Memories", "memories")
+	viper.SetDefault("Telegram.Commands.Language", "language")
+	viper.SetDefault("Telegram.Commands.Token", "token")
+	viper.SetDefault("Telegram.Commands.Search", "search")
+
+	// Telegram Command descriptions, per language
+	viper.SetDefault("Telegram.DefaultLanguage", "en")
+	viper.SetDefault("Telegram.CommandDescriptions.en.Help", "Get some help")
+	viper.SetDefault("Telegram.CommandDescriptions.en.Info", "Get the name of the current album")
+	viper.SetDefault("Telegram.CommandDescriptions.en.NewAlbum", "Start a new album")
+	viper.SetDefault("Telegram.CommandDescriptions.en.Share", "Get the sharing links of all albums")
+	viper.SetDefault("Telegram.CommandDescriptions.en.Browse", "Get a link to browse all albums")
+	viper.SetDefault("Telegram.CommandDescriptions.en.Digest", "Subscribe to or unsubscribe from the digest")
+	viper.SetDefault("Telegram.CommandDescriptions.en.Memories", "Receive or stop receiving your memories of the day")
+	viper.SetDefault("Telegram.CommandDescriptions.en.Language", "Change the language")
+	viper.SetDefault("Telegram.CommandDescriptions.en.Token", "Manage the API tokens")
+	viper.SetDefault("Telegram.CommandDescriptions.en.Search", "Search photos and videos")
+	viper.SetDefault("Telegram.CommandDescriptions.fr.Help", "Obtenir de l'aide")
+	viper.SetDefault("Telegram.CommandDescriptions.fr.Info", "Obtenir le nom de l'album en cours")
+	viper.SetDefault("Telegram.CommandDescriptions.fr.NewAlbum", "Commencer un nouvel album")
+	viper.SetDefault("Telegram.CommandDescriptions.fr.Share", "Obtenir les liens de partage des albums")
+	viper.SetDefault("Telegram.CommandDescriptions.fr.Browse", "Obtenir un lien pour parcourir tous les albums")
+	viper.SetDefault("Telegram.CommandDescriptions.fr.Digest", "S'abonner ou se désabonner du résumé")
+	viper.SetDefault("Telegram.CommandDescriptions.fr.Memories", "Recevoir ou ne plus recevoir vos souvenirs du jour")
+	viper.SetDefault("Telegram.CommandDescriptions.fr.Language", "Changer de langue")
+	viper.SetDefault("Telegram.CommandDescriptions.fr.Token", "Gérer les jetons d'API")
+	viper.SetDefault("Telegram.CommandDescriptions.fr.Search", "Rechercher des photos et vidéos")
+
+	// Digest of the new media
+	viper.SetDefault("Telegram.Digest.Enabled", false)
+	viper.SetDefault("Telegram.Digest.Time", "19:00")
+	viper.SetDefault("Telegram.Digest.Frequency", "daily")
+	viper.SetDefault("Telegram.Digest.Weekday", "Sunday")
+	viper.SetDefault("Telegram.Digest.ReplaceForwards", false)
+
+	// "On this day" memories
+	viper.SetDefault("Telegram.Memories.Enabled", false)
+	viper.SetDefault("Telegram.Memories.Time", "09:00")
+	viper.SetDefault("Telegram.Memories.Count", 3)
 
 	// Web Interface
 	viper.SetDefault("WebInterface.SiteName", "My photo album")
@@ -65,6 +196,127 @@ func initConfig() {
 	viper.SetDefault("WebInterface.I18n.AllAlbums", "All my albums")
 	viper.SetDefault("WebInterface.I18n.Bio", "Hello, I'm the photo bot. Here are all the photos and videos collected so far.")
 	viper.SetDefault("WebInterface.I18n.LastMedia", "My last photos and videos")
+	viper.SetDefault("WebInterface.I18n.LanguageName", "English")
+	viper.SetDefault("WebInterface.I18n.NotFound", "File not found")
+	viper.SetDefault("WebInterface.I18n.ServerError", "Internal server error")
+	viper.SetDefault("WebInterface.I18n.DateFormat", "January 2006")
+	viper.SetDefault("WebInterface.I18n.Upload", "Add photos and videos (or drop them here)")
+	viper.SetDefault("WebInterface.I18n.UploadButton", "Upload")
+	viper.SetDefault("WebInterface.I18n.UploadError", "The upload failed")
+	viper.SetDefault("WebInterface.I18n.UploadCaption", "Caption (optional)")
+	viper.SetDefault("WebInterface.I18n.Download", "Download all photos and videos")
+	viper.SetDefault("WebInterface.I18n.Select", "Select photos and videos")
+	viper.SetDefault("WebInterface.I18n.DownloadSelection", "Download the selection")
+	viper.SetDefault("WebInterface.I18n.Cancel", "Cancel")
+	viper.SetDefault("WebInterface.I18n.Previous", "Previous")
+	viper.SetDefault("WebInterface.I18n.Next", "Next")
+	viper.SetDefault("WebInterface.I18n.Slideshow", "Slideshow")
+	viper.SetDefault("WebInterface.I18n.Map", "Map")
+	viper.SetDefault("WebInterface.I18n.ZoomIn", "Zoom in")
+	viper.SetDefault("WebInterface.I18n.ZoomOut", "Zoom out")
+	viper.SetDefault("WebInterface.I18n.NoLocation", "No photo or video has a location yet.")
+	viper.SetDefault("WebInterface.I18n.Timeline", "Timeline")
+	viper.SetDefault("WebInterface.I18n.Search", "Search")
+	viper.SetDefault("WebInterface.I18n.NoResult", "Nothing found.")
+	viper.SetDefault("WebInterface.I18n.Tags", "Tags")
+	viper.SetDefault("WebInterface.I18n.Favorite", "Favorite")
+	viper.SetDefault("WebInterface.I18n.Highlights", "Highlights")
+	viper.SetDefault("WebInterface.I18n.AllMedia", "All photos and videos")
+	viper.SetDefault("WebInterface.I18n.Comments", "Comments")
+	viper.SetDefault("WebInterface.I18n.AddComment", "Add a comment")
+	viper.SetDefault("WebInterface.I18n.Administration", "Administration")
+	viper.SetDefault("WebInterface.I18n.Rename", "Rename")
+	viper.SetDefault("WebInterface.I18n.SetCover", "Use as cover")
+	viper.SetDefault("WebInterface.I18n.Delete", "Delete")
+	viper.SetDefault("WebInterface.I18n.ConfirmDelete", "Delete this media for good?")
+	viper.SetDefault("WebInterface.I18n.MoveUp", "Move up")
+	viper.SetDefault("WebInterface.I18n.MoveDown", "Move down")
+	viper.SetDefault("WebInterface.I18n.APITokens", "API tokens")
+	viper.SetDefault("WebInterface.I18n.TokenName", "Name")
+	viper.SetDefault("WebInterface.I18n.TokenScopes", "Scopes")
+	viper.SetDefault("WebInterface.I18n.TokenCreatedBy", "Created by")
+	viper.SetDefault("WebInterface.I18n.CreateToken", "Create")
+	viper.SetDefault("WebInterface.I18n.RevokeToken", "Revoke")
+	viper.SetDefault("WebInterface.I18n.ConfirmRevoke", "Revoke this token for good?")
+	viper.SetDefault("WebInterface.I18n.TokenSecret", "Keep this token secret, it will not be shown again:")
+	viper.SetDefault("WebInterface.DefaultLanguage", "en")
+
+	// Uploads through the web interface
+	viper.SetDefault("WebInterface.Upload.Enabled", false)
+	viper.SetDefault("WebInterface.Upload.MaxFileSize", 200)     // in MB
+	viper.SetDefault("WebInterface.Upload.MaxRequestSize", 1000) // in MB
+
+	// Number of media per page of an album
+	viper.SetDefault("WebInterface.PageSize", 60)
+	// Origins allowed to embed the album widget (none by default)
+	viper.SetDefault("WebInterface.FrameAncestors", []string{})
+	// Validity of the image URLs of the link previews
+	viper.SetDefault("WebInterface.PreviewValidity", 3600) // in seconds
+	// Number of media in the Atom feeds
+	viper.SetDefault("WebInterface.FeedSize", 50)
+	// Use the most favorited photo as default cover of the albums
+	viper.SetDefault("WebInterface.FavoriteCover", false)
+
+	// Slideshow
+	viper.SetDefault("WebInterface.Slideshow.Interval", 5) // in seconds
+	viper.SetDefault("WebInterface.Slideshow.Shuffle", false)
+	viper.SetDefault("WebInterface.Slideshow.Captions", true)
+
+	// Map
+	viper.SetDefault("WebInterface.Map.TileURL", "https://tile.openstreetmap.org/{z}/{x}/{y}.png")
+	viper.SetDefault("WebInterface.Map.Attribution", "© OpenStreetMap contributors")
+	viper.SetDefault("WebInterface.Map.MaxZoom", 19)
+	viper.SetDefault("Telegram.GeotagWindow", 30) // in minutes
+
+	// Web Interface, translated in French
+	viper.SetDefault("WebInterface.Translations.fr.SiteName", "Mon album photo")
+	viper.SetDefault("WebInterface.Translations.fr.AllAlbums", "Tous mes albums")
+	viper.SetDefault("WebInterface.Translations.fr.Bio", "Bonjour, je suis le bot photo. Voici toutes les photos et vidéos collectées jusqu'à présent.")
+	viper.SetDefault("WebInterface.Translations.fr.LastMedia", "Mes dernières photos et vidéos")
+	viper.SetDefault("WebInterface.Translations.fr.LanguageName", "Français")
+	viper.SetDefault("WebInterface.Translations.fr.NotFound", "Fichier introuvable")
+	viper.SetDefault("WebInterface.Translations.fr.ServerError", "Erreur interne du serveur")
+	viper.SetDefault("WebInterface.Translations.fr.DateFormat", "January 2006")
+	viper.SetDefault("WebInterface.Translations.fr.Upload", "Ajouter des photos et vidéos (ou les déposer ici)")
+	viper.SetDefault("WebInterface.Translations.fr.UploadButton", "Envoyer")
+	viper.SetDefault("WebInterface.Translations.fr.UploadError", "L'envoi a échoué")
+	viper.SetDefault("WebInterface.Translations.fr.UploadCaption", "Légende (facultative)")
+	viper.SetDefault("WebInterface.Translations.fr.Download", "Télécharger toutes les photos et vidéos")
+	viper.SetDefault("WebInterface.Translations.fr.Select", "Sélectionner des photos et vidéos")
+	viper.SetDefault("WebInterface.Translations.fr.DownloadSelection", "Télécharger la sélection")
+	viper.SetDefault("WebInterface.Translations.fr.Cancel", "Annuler")
+	viper.SetDefault("WebInterface.Translations.fr.Previous", "Précédent")
+	viper.SetDefault("WebInterface.Translations.fr.Next", "Suivant")
+	viper.SetDefault("WebInterface.Translations.fr.Slideshow", "Diaporama")
+	viper.SetDefault("WebInterface.Translations.fr.Map", "Carte")
+	viper.SetDefault("WebInterface.Translations.fr.ZoomIn", "Zoomer")
+	viper.SetDefault("WebInterface.Translations.fr.ZoomOut", "Dézoomer")
+	viper.SetDefault("WebInterface.Translations.fr.NoLocation", "Aucune photo ni vidéo n'a encore de position.")
+	viper.SetDefault("WebInterface.Translations.fr.Timeline", "Chronologie")
+	viper.SetDefault("WebInterface.Translations.fr.Search", "Rechercher")
+	viper.SetDefault("WebInterface.Translations.fr.NoResult", "Aucun résultat.")
+	viper.SetDefault("WebInterface.Translations.fr.Tags", "Mots-clés")
+	viper.SetDefault("WebInterface.Translations.fr.Favorite", "Favori")
+	viper.SetDefault("WebInterface.Translations.fr.Highlights", "Meilleurs moments")
+	viper.SetDefault("WebInterface.Translations.fr.AllMedia", "Toutes les photos et vidéos")
+	viper.SetDefault("WebInterface.Translations.fr.Comments", "Commentaires")
+	viper.SetDefault("WebInterface.Translations.fr.AddComment", "Ajouter un commentaire")
+	viper.SetDefault("WebInterface.Translations.fr.Administration", "Administration")
+	viper.SetDefault("WebInterface.Translations.fr.Rename", "Renommer")
+	viper.SetDefault("WebInterface.Translations.fr.SetCover", "Utiliser comme couverture")
+	viper.SetDefault("WebInterface.Translations.fr.Delete", "Supprimer")
+	viper.SetDefault("WebInterface.Translations.fr.ConfirmDelete", "Supprimer définitivement ce média ?")
+	viper.SetDefault("WebInterface.Translations.fr.MoveUp", "Monter")
+	viper.SetDefault("WebInterface.Translations.fr.MoveDown", "Descendre")
+	viper.SetDefault("WebInterface.Translations.fr.APITokens", "Jetons d'API")
+	viper.SetDefault("WebInterface.Translations.fr.TokenName", "Nom")
+	viper.SetDefault("WebInterface.Translations.fr.TokenScopes", "Droits")
+	viper.SetDefault("WebInterface.Translations.fr.TokenCreatedBy", "Créé par")
+	viper.SetDefault("WebInterface.Translations.fr.CreateToken", "Créer")
+	viper.SetDefault("WebInterface.Translations.fr.RevokeToken", "Révoquer")
+	viper.SetDefault("WebInterface.Translations.fr.ConfirmRevoke", "Révoquer définitivement ce jeton ?")
+	viper.SetDefault("WebInterface.Translations.fr.TokenSecret", "Gardez ce jeton secret, il ne sera plus affiché :")
+	viper.SetDefault("WebInterface.Translations.fr.Months", []string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"})
 
 	viper.SetConfigName("photo-bot") // name of config file (without extension)
 	viper.AddConfigPath("/etc/photo-bot/")
@@ -81,6 +333,33 @@ func initConfig() {
 	if err != nil {
 		panic(fmt.Errorf("Cannot read config file: %s\n", err))
 	}
+
+	// Read the config file again, without the defaults, to tell the strings
+	// set by the operator from the built-in ones
+	configFile = viper.New()
+	configFile.SetConfigFile(viper.ConfigFileUsed())
+	err = configFile.ReadInConfig()
+	if err != nil {
+		panic(fmt.Errorf("Cannot read config file: %s\n", err))
+	}
+}
+
+// configFile holds the settings of the config file only
+var configFile *viper.Viper
+
+// localizedKey returns the key of the translated string if there is one,
+// unless only its fallback has been set in the config file: the strings set
+// by the operator take precedence over the built-in translations.
+func localizedKey(translated string, fallback string) string {
+	if !viper.IsSet(translated) {
+		return fallback
+	}
+
+	if configFile != nil && configFile.IsSet(fallback) && !configFile.IsSet(translated) {
+		return fallback
+	}
+
+	return translated
 }
 
 func initLogFile() {
@@ -124,6 +403,20 @@ func validateConfig() {
 		log.Fatal("A list of AuthorizedUsers must be given!")
 	}
 
+	for _, admin := range viper.GetStringSlice("Telegram.Admins") {
+		found := false
+		for _, user := range authorizedUsersList {
+			found = found || user == admin
+		}
+		if !found {
+			log.Fatalf("Admin '%s' is not part of the AuthorizedUsers!", admin)
+		}
+	}
+
+	if _, ok := getCommandDescriptionsFromConfig()[viper.GetString("Telegram.DefaultLanguage")]; !ok {
+		log.Fatal("The DefaultLanguage has no CommandDescriptions!")
+	}
+
 	if viper.GetString("WebInterface.OIDC.DiscoveryUrl") == "" {
 		log.Fatal("No OpenID Connect Discovery URL provided!")
 	}
@@ -151,32 +444,183 @@ func validateConfig() {
 	if viper.GetString("Telegram.TokenGenerator.AuthenticationKey") == "" {
 		log.Fatal("No Token Generator Authentication Key provided!")
 	}
+
+	if viper.GetBool("Telegram.Digest.Enabled") {
+		_, _, err := parseTimeOfDay(viper.GetString("Telegram.Digest.Time"))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		frequency := viper.GetString("Telegram.Digest.Frequency")
+		if frequency != "daily" && frequency != "weekly" {
+			log.Fatalf("The Digest Frequency must be either 'daily' or 'weekly', got '%s'!", frequency)
+		}
+
+		_, err = parseWeekday(viper.GetString("Telegram.Digest.Weekday"))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if viper.GetInt("WebInterface.Slideshow.Interval") <= 0 {
+		log.Fatal("The Slideshow Interval cannot be zero or negative!")
+	}
+
+	if viper.GetInt("WebInterface.PageSize") <= 0 {
+		log.Fatal("The PageSize cannot be zero or negative!")
+	}
+
+	if viper.GetBool("WebInterface.Upload.Enabled") && viper.GetInt64("WebInterface.Upload.MaxFileSize") <= 0 {
+		log.Fatal("The Upload MaxFileSize cannot be zero or negative!")
+	}
+	if viper.GetBool("WebInterface.Upload.Enabled") && viper.GetInt64("WebInterface.Upload.MaxRequestSize") < viper.GetInt64("WebInterface.Upload.MaxFileSize") {
+		log.Fatal("The Upload MaxRequestSize cannot be lower than the MaxFileSize!")
+	}
+
+	if viper.GetBool("Telegram.Memories.Enabled") {
+		_, _, err := parseTimeOfDay(viper.GetString("Telegram.Memories.Time"))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if viper.GetInt("Telegram.Memories.Count") <= 0 {
+			log.Fatal("The Memories Count cannot be zero or negative!")
+		}
+	}
 }
 
 func getCommandsFromConfig() TelegramCommands {
+	// Telegram only accepts lowercase commands
 	return TelegramCommands{
-		Help:     viper.GetString("Telegram.Commands.Help"),
-		NewAlbum: viper.GetString("Telegram.Commands.NewAlbum"),
-		Info:     viper.GetString("Telegram.Commands.Info"),
-		Share:    viper.GetString("Telegram.Commands.Share"),
-		Browse:   viper.GetString("Telegram.Commands.Browse"),
+		Help:     strings.ToLower(viper.GetString("Telegram.Commands.Help")),
+		NewAlbum: strings.ToLower(viper.GetString("Telegram.Commands.NewAlbum")),
+		Info:     strings.ToLower(viper.GetString("Telegram.Commands.Info")),
+		Share:    strings.ToLower(viper.GetString("Telegram.Commands.Share")),
+		Browse:   strings.ToLower(viper.GetString("Telegram.Commands.Browse")),
+		Digest:   strings.ToLower(viper.GetString("Telegram.Commands.Digest")),
+		Memories: strings.ToLower(viper.GetString("Telegram.Commands.Memories")),
+		Language: strings.ToLower(viper.GetString("Telegram.Commands.Language")),
+		Token:    strings.ToLower(viper.GetString("Telegram.Commands.Token")),
+		Search:   strings.ToLower(viper.GetString("Telegram.Commands.Search")),
+	}
+}
+
+func getCommandDescriptionsFromConfig() map[string]map[string]string {
+	return getLocalizedStringsFromConfig("telegram.commanddescriptions.")
+}
+
+// getLocalizedStringsFromConfig returns the strings found under the given
+// prefix, by language (prefix.<language>.<key>). Keys are iterated
+// individually so that the strings set in the config file are merged with
+// the default ones.
+func getLocalizedStringsFromConfig(prefix string) map[string]map[string]string {
+	localized := make(map[string]map[string]string)
+	for _, key := range viper.AllKeys() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(key, prefix), ".", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		if localized[parts[0]] == nil {
+			localized[parts[0]] = make(map[string]string)
+		}
+		localized[parts[0]][parts[1]] = viper.GetString(key)
 	}
+
+	return localized
 }
 
-func getMessagesFromConfig() TelegramMessages {
+// getMessagesFromConfig returns the Telegram messages in the given language.
+// Untranslated messages fallback to the ones in Telegram.Messages.
+func getMessagesFromConfig(lang string) TelegramMessages {
+	get := func(key string) string {
+		return viper.GetString(localizedKey("Telegram.Translations."+lang+"."+key, "Telegram.Messages."+key))
+	}
+
 	return TelegramMessages{
-		Forbidden:        viper.GetString("Telegram.Messages.Forbidden"),
-		Help:             viper.GetString("Telegram.Messages.Help"),
-		MissingAlbumName: viper.GetString("Telegram.Messages.MissingAlbumName"),
-		ServerError:      viper.GetString("Telegram.Messages.ServerError"),
-		AlbumCreated:     viper.GetString("Telegram.Messages.AlbumCreated"),
-		DoNotUnderstand:  viper.GetString("Telegram.Messages.DoNotUnderstand"),
-		Info:             viper.GetString("Telegram.Messages.Info"),
-		InfoNoAlbum:      viper.GetString("Telegram.Messages.InfoNoAlbum"),
-		NoUsername:       viper.GetString("Telegram.Messages.NoUsername"),
-		SharedAlbum:      viper.GetString("Telegram.Messages.SharedAlbum"),
-		SharedGlobal:     viper.GetString("Telegram.Messages.SharedGlobal"),
-		ThankYouMedia:    viper.GetString("Telegram.Messages.ThankYouMedia"),
+		Forbidden:           get("Forbidden"),
+		Help:                get("Help"),
+		MissingAlbumName:    get("MissingAlbumName"),
+		ServerError:         get("ServerError"),
+		AlbumCreated:        get("AlbumCreated"),
+		DoNotUnderstand:     get("DoNotUnderstand"),
+		Info:                get("Info"),
+		InfoNoAlbum:         get("InfoNoAlbum"),
+		NoUsername:          get("NoUsername"),
+		SharedAlbum:         get("SharedAlbum"),
+		SharedGlobal:        get("SharedGlobal"),
+		ThankYouMedia:       get("ThankYouMedia"),
+		ThankYouLocation:    get("ThankYouLocation"),
+		Digest:              get("Digest"),
+		DigestAlbum:         get("DigestAlbum"),
+		DigestSubscribed:    get("DigestSubscribed"),
+		DigestStopped:       get("DigestStopped"),
+		Memories:            get("Memories"),
+		MemoryMedia:         get("MemoryMedia"),
+		MemoriesOptIn:       get("MemoriesOptIn"),
+		MemoriesOptOut:      get("MemoriesOptOut"),
+		Language:            get("Language"),
+		LanguageChanged:     get("LanguageChanged"),
+		LanguageUnknown:     get("LanguageUnknown"),
+		TokenCreated:        get("TokenCreated"),
+		TokenNotCreated:     get("TokenNotCreated"),
+		TokenList:           get("TokenList"),
+		TokenListEmpty:      get("TokenListEmpty"),
+		TokenRevoked:        get("TokenRevoked"),
+		TokenUnknown:        get("TokenUnknown"),
+		TokenUsage:          get("TokenUsage"),
+		TagsUpdated:         get("TagsUpdated"),
+		TagsNone:            get("TagsNone"),
+		CommentAdded:        get("CommentAdded"),
+		CommentInvalid:      get("CommentInvalid"),
+		CommentNotification: get("CommentNotification"),
+		SearchUsage:         get("SearchUsage"),
+		SearchNoResult:      get("SearchNoResult"),
+		SearchResults:       get("SearchResults"),
+		SearchMedia:         get("SearchMedia"),
+	}
+}
+
+func getMessageCatalogFromConfig() *MessageCatalog {
+	defaultLanguage := viper.GetString("Telegram.DefaultLanguage")
+	messages := map[string]TelegramMessages{
+		defaultLanguage: getMessagesFromConfig(defaultLanguage),
+	}
+	for lang := range getLocalizedStringsFromConfig("telegram.translations.") {
+		messages[lang] = getMessagesFromConfig(lang)
+	}
+
+	return NewMessageCatalog(defaultLanguage, messages)
+}
+
+func getDigestJobFromConfig(bot *TelegramBot) ScheduledJob {
+	// The config has already been validated by validateConfig
+	hour, minute, _ := parseTimeOfDay(viper.GetString("Telegram.Digest.Time"))
+	weekday, _ := parseWeekday(viper.GetString("Telegram.Digest.Weekday"))
+
+	return ScheduledJob{
+		Name:    "digest",
+		Hour:    hour,
+		Minute:  minute,
+		Weekly:  viper.GetString("Telegram.Digest.Frequency") == "weekly",
+		Weekday: weekday,
+		Run:     bot.SendDigests,
+	}
+}
+
+func getMemoriesJobFromConfig(bot *TelegramBot) ScheduledJob {
+	// The config has already been validated by validateConfig
+	hour, minute, _ := parseTimeOfDay(viper.GetString("Telegram.Memories.Time"))
+
+	return ScheduledJob{
+		Name:   "memories",
+		Hour:   hour,
+		Minute: minute,
+		Run:    bot.SendMemories,
 	}
 }
 
@@ -193,15 +637,77 @@ func getSecretKey(configKey string, minLength int) []byte {
 	return key
 }
 
-func getWebI18nFromConfig() I18n {
+// getWebI18nFromConfig returns the web interface strings in the given
+// language. Untranslated strings fallback to the ones in WebInterface.I18n.
+func getWebI18nFromConfig(lang string) I18n {
+	key := func(key string) string {
+		return localizedKey("WebInterface.Translations."+lang+"."+key, "WebInterface.I18n."+key)
+	}
+
 	var i18n I18n
-	i18n.SiteName = viper.GetString("WebInterface.SiteName")
-	i18n.AllAlbums = viper.GetString("WebInterface.I18n.AllAlbums")
-	i18n.Bio = viper.GetString("WebInterface.I18n.Bio")
-	i18n.LastMedia = viper.GetString("WebInterface.I18n.LastMedia")
+	i18n.SiteName = viper.GetString(localizedKey("WebInterface.Translations."+lang+".SiteName", "WebInterface.SiteName"))
+	i18n.AllAlbums = viper.GetString(key("AllAlbums"))
+	i18n.Bio = viper.GetString(key("Bio"))
+	i18n.LastMedia = viper.GetString(key("LastMedia"))
+	i18n.LanguageName = viper.GetString(key("LanguageName"))
+	i18n.NotFound = viper.GetString(key("NotFound"))
+	i18n.ServerError = viper.GetString(key("ServerError"))
+	i18n.Upload = viper.GetString(key("Upload"))
+	i18n.UploadButton = viper.GetString(key("UploadButton"))
+	i18n.UploadError = viper.GetString(key("UploadError"))
+	i18n.UploadCaption = viper.GetString(key("UploadCaption"))
+	i18n.Download = viper.GetString(key("Download"))
+	i18n.Select = viper.GetString(key("Select"))
+	i18n.DownloadSelection = viper.GetString(key("DownloadSelection"))
+	i18n.Cancel = viper.GetString(key("Cancel"))
+	i18n.Previous = viper.GetString(key("Previous"))
+	i18n.Next = viper.GetString(key("Next"))
+	i18n.Slideshow = viper.GetString(key("Slideshow"))
+	i18n.Map = viper.GetString(key("Map"))
+	i18n.ZoomIn = viper.GetString(key("ZoomIn"))
+	i18n.ZoomOut = viper.GetString(key("ZoomOut"))
+	i18n.NoLocation = viper.GetString(key("NoLocation"))
+	i18n.Timeline = viper.GetString(key("Timeline"))
+	i18n.Search = viper.GetString(key("Search"))
+	i18n.NoResult = viper.GetString(key("NoResult"))
+	i18n.Tags = viper.GetString(key("Tags"))
+	i18n.Favorite = viper.GetString(key("Favorite"))
+	i18n.Highlights = viper.GetString(key("Highlights"))
+	i18n.AllMedia = viper.GetString(key("AllMedia"))
+	i18n.Comments = viper.GetString(key("Comments"))
+	i18n.AddComment = viper.GetString(key("AddComment"))
+	i18n.Administration = viper.GetString(key("Administration"))
+	i18n.Rename = viper.GetString(key("Rename"))
+	i18n.SetCover = viper.GetString(key("SetCover"))
+	i18n.Delete = viper.GetString(key("Delete"))
+	i18n.ConfirmDelete = viper.GetString(key("ConfirmDelete"))
+	i18n.MoveUp = viper.GetString(key("MoveUp"))
+	i18n.MoveDown = viper.GetString(key("MoveDown"))
+	i18n.APITokens = viper.GetString(key("APITokens"))
+	i18n.TokenName = viper.GetString(key("TokenName"))
+	i18n.TokenScopes = viper.GetString(key("TokenScopes"))
+	i18n.TokenCreatedBy = viper.GetString(key("TokenCreatedBy"))
+	i18n.CreateToken = viper.GetString(key("CreateToken"))
+	i18n.RevokeToken = viper.GetString(key("RevokeToken"))
+	i18n.ConfirmRevoke = viper.GetString(key("ConfirmRevoke"))
+	i18n.TokenSecret = viper.GetString(key("TokenSecret"))
+	i18n.DateFormat = viper.GetString(key("DateFormat"))
+	i18n.Months = viper.GetStringSlice(key("Months"))
 	return i18n
 }
 
+func getWebCatalogFromConfig() *WebCatalog {
+	defaultLanguage := viper.GetString("WebInterface.DefaultLanguage")
+	translations := map[string]I18n{
+		defaultLanguage: getWebI18nFromConfig(defaultLanguage),
+	}
+	for lang := range getLocalizedStringsFromConfig("webinterface.translations.") {
+		translations[lang] = getWebI18nFromConfig(lang)
+	}
+
+	return NewWebCatalog(defaultLanguage, translations)
+}
+
 func main() {
 	initConfig()
 	validateConfig()
@@ -221,6 +727,7 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	mediaStore.GeotagWindow = time.Duration(viper.GetInt("Telegram.GeotagWindow")) * time.Minute
 
 	// Create the Token Generator
 	tokenAuthenticationKey := getSecretKey("Telegram.TokenGenerator.AuthenticationKey", 32)
@@ -235,26 +742,81 @@ func main() {
 		panic(err)
 	}
 
+	// Create the MessageDB
+	messageDB, err := InitMessageDB(filepath.Join(targetDir, "db", "messages.yaml"))
+	if err != nil {
+		panic(err)
+	}
+
+	// Create the PreferencesDB
+	preferencesDB, err := InitPreferencesDB(filepath.Join(targetDir, "db", "preferences.yaml"))
+	if err != nil {
+		panic(err)
+	}
+
+	// Create the API Token Store
+	apiTokens, err := InitAPITokenStore(filepath.Join(targetDir, "db", "tokens.yaml"))
+	if err != nil {
+		panic(err)
+	}
+
+	// Create the favorites of the web users
+	favoritesDB, err := InitFavoritesDB(filepath.Join(targetDir, "db", "favorites.yaml"))
+	if err != nil {
+		panic(err)
+	}
+	if viper.GetBool("WebInterface.FavoriteCover") {
+		mediaStore.Favorites = favoritesDB
+	}
+
+	// Create the audit log of the admin interface
+	auditLog, err := InitAuditLog(filepath.Join(targetDir, "db", "audit.yaml"))
+	if err != nil {
+		panic(err)
+	}
+
 	// Create the Bot
 	photoBot := NewTelegramBot()
 	photoBot.RetryDelay = time.Duration(viper.GetInt("Telegram.RetryDelay")) * time.Second
 	photoBot.NewUpdateTimeout = viper.GetInt("Telegram.NewUpdateTimeout")
 	photoBot.Commands = getCommandsFromConfig()
-	photoBot.Messages = getMessagesFromConfig()
+	photoBot.Messages = getMessageCatalogFromConfig()
+	photoBot.CommandDescriptions = getCommandDescriptionsFromConfig()
+	photoBot.DefaultLanguage = viper.GetString("Telegram.DefaultLanguage")
 	photoBot.WebPublicURL = viper.GetString("WebInterface.PublicURL")
 	photoBot.MediaStore = mediaStore
 	photoBot.ChatDB = chatDB
+	photoBot.MessageDB = messageDB
+	photoBot.Preferences = preferencesDB
+	photoBot.APITokens = apiTokens
 	photoBot.TokenGenerator = tokenGenerator
 	photoBot.GlobalTokenValidity = viper.GetInt("Telegram.TokenGenerator.GlobalValidity")
 	photoBot.PerAlbumTokenValidity = viper.GetInt("Telegram.TokenGenerator.PerAlbumValidity")
+	photoBot.DigestEnabled = viper.GetBool("Telegram.Digest.Enabled")
+	photoBot.DigestReplacesForwards = viper.GetBool("Telegram.Digest.ReplaceForwards")
+	photoBot.MemoriesEnabled = viper.GetBool("Telegram.Memories.Enabled")
+	photoBot.MemoriesCount = viper.GetInt("Telegram.Memories.Count")
 
 	// Fill the authorized users
 	for _, item := range viper.GetStringSlice("Telegram.AuthorizedUsers") {
 		photoBot.AuthorizedUsers[item] = true
 	}
+	for _, item := range viper.GetStringSlice("Telegram.Admins") {
+		photoBot.Admins[item] = true
+	}
 
 	// Start the bot
 	photoBot.StartBot(viper.GetString("Telegram.Token"), viper.GetBool("Telegram.Debug"))
+	photoBot.PublishCommands()
+
+	// Schedule the periodic jobs
+	scheduler := &Scheduler{}
+	if photoBot.DigestEnabled {
+		scheduler.Add(getDigestJobFromConfig(photoBot))
+	}
+	if photoBot.MemoriesEnabled {
+		scheduler.Add(getMemoriesJobFromConfig(photoBot))
+	}
 
 	// Setup the web interface
 	statikFS, err := fs.New()
@@ -266,7 +828,33 @@ func main() {
 		panic(err)
 	}
 	web.MediaStore = mediaStore
-	web.I18n = getWebI18nFromConfig()
+	web.I18n = getWebCatalogFromConfig()
+	web.Upload = UploadSettings{
+		Enabled:        viper.GetBool("WebInterface.Upload.Enabled"),
+		MaxFileSize:    viper.GetInt64("WebInterface.Upload.MaxFileSize") * 1024 * 1024,
+		MaxRequestSize: viper.GetInt64("WebInterface.Upload.MaxRequestSize") * 1024 * 1024,
+	}
+	web.Audit = auditLog
+	web.Favorites = favoritesDB
+	web.MessageDB = messageDB
+	web.APITokens = apiTokens
+	web.PageSize = viper.GetInt("WebInterface.PageSize")
+	web.FeedSize = viper.GetInt("WebInterface.FeedSize")
+	web.PublicURL = viper.GetString("WebInterface.PublicURL")
+	web.TokenGenerator = tokenGenerator
+	web.PreviewValidity = time.Duration(viper.GetInt("WebInterface.PreviewValidity")) * time.Second
+	web.FrameAncestors = viper.GetStringSlice("WebInterface.FrameAncestors")
+	web.Map = MapSettings{
+		TileURL:     viper.GetString("WebInterface.Map.TileURL"),
+		Attribution: viper.GetString("WebInterface.Map.Attribution"),
+		MaxZoom:     viper.GetInt("WebInterface.Map.MaxZoom"),
+	}
+	web.Headers = SecurityHeaders{ImageSources: []string{web.Map.TileSource()}}
+	web.Slideshow = SlideshowSettings{
+		Interval: viper.GetInt("WebInterface.Slideshow.Interval"),
+		Shuffle:  viper.GetBool("WebInterface.Slideshow.Shuffle"),
+		Captions: viper.GetBool("WebInterface.Slideshow.Captions"),
+	}
 
 	// Setup the security frontend
 	var oidc OpenIdSettings = OpenIdSettings{
@@ -291,14 +879,22 @@ func main() {
 	}
 	securityFrontend.GlobalTokenValidity = viper.GetInt("Telegram.TokenGenerator.GlobalValidity")
 	securityFrontend.PerAlbumTokenValidity = viper.GetInt("Telegram.TokenGenerator.PerAlbumValidity")
+	securityFrontend.APITokens = apiTokens
+	securityFrontend.PublicURL = viper.GetString("WebInterface.PublicURL")
+	securityFrontend.WebDAVUsers = viper.GetStringMapString("WebInterface.WebDAV.Users")
+	securityFrontend.Admins = make(map[string]bool)
+	for _, item := range viper.GetStringSlice("WebInterface.Admins") {
+		securityFrontend.Admins[item] = true
+	}
 
 	// Put the Web Interface behind the security frontend
 	securityFrontend.Protected = web
 
 	initLogFile()
 	go photoBot.Process()
+	scheduler.Start()
 
-	err = ServeWebInterface(viper.GetString("WebInterface.Listen"), securityFrontend, statikFS)
+	err = ServeWebInterface(viper.GetString("WebInterface.Listen"), securityFrontend, statikFS, web.Headers)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MapSettings configures the tile server used by the map pages. Any server
+// following the OpenStreetMap URL scheme can be used, including a self-hosted
+// one.
+type MapSettings struct {
+	TileURL     string // such as https://tile.openstreetmap.org/{z}/{x}/{y}.png
+	Attribution string
+	MaxZoom     int
+}
+
+// TileSource returns the origin of the tiles, to be allowed by the Content
+// Security Policy. Placeholders in the host name, such as the "{s}"
+// subdomain, become wildcards.
+func (settings MapSettings) TileSource() string {
+	i := strings.Index(settings.TileURL, "://")
+	if i < 0 {
+		return ""
+	}
+
+	host := settings.TileURL[i+3:]
+	if j := strings.IndexAny(host, "/?#"); j >= 0 {
+		host = host[:j]
+	}
+
+	labels := strings.Split(host, ".")
+	for k, label := range labels {
+		if strings.Contains(label, "{") {
+			labels[k] = "*"
+		}
+	}
+
+	return settings.TileURL[:i+3] + strings.Join(labels, ".")
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties interface{}     `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"` // []float64 for a Point, [][]float64 for a LineString
+}
+
+// Features are told apart by their kind: "media", "place" or "route"
+type geoJSONProperties struct {
+	Kind      string    `json:"kind"`
+	Album     string    `json:"album"`
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Caption   string    `json:"caption,omitempty"`
+	Date      time.Time `json:"date"`
+	URL       string    `json:"url"`
+	Thumbnail string    `json:"thumbnail,omitempty"`
+}
+
+func newGeoJSONFeatureCollection() geoJSONFeatureCollection {
+	return geoJSONFeatureCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+}
+
+// addAlbum adds the geotagged media of an album to the collection. URLs are
+// relative to the base path of the web interface.
+func (collection *geoJSONFeatureCollection) addAlbum(album Album, basePath string) {
+	id := album.ID
+	if id == "" {
+		id = "latest"
+	}
+	albumURL := basePath + "/album/" + url.PathEscape(id) + "/"
+
+	for _, media := range album.Media {
+		if media.Location == nil {
+			continue
+		}
+
+		properties := geoJSONProperties{
+			Kind:    "media",
+			Album:   id,
+			ID:      media.ID,
+			Type:    media.Type,
+			Caption: media.Caption,
+			Date:    media.TakenDate(),
+			URL:     albumURL + "media/" + url.PathEscape(media.ID) + "/",
+		}
+		if photo := findFileWithSuffix(media.Files, ".jpeg"); photo != "" {
+			properties.Thumbnail = albumURL + "raw/" + url.PathEscape(photo)
+		}
+
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "Point",
+				Coordinates: media.Location.coordinates(),
+			},
+			Properties: properties,
+		})
+	}
+}
+
+type geoJSONPlaceProperties struct {
+	Kind  string    `json:"kind"`
+	Title string    `json:"title"`
+	Date  time.Time `json:"date"`
+}
+
+type geoJSONRouteProperties struct {
+	Kind string `json:"kind"`
+}
+
+// addTrack adds the route followed during an album to the collection, along
+// with the places (Telegram venues) shared on the way
+func (collection *geoJSONFeatureCollection) addTrack(track []TrackPoint) {
+	if len(track) == 0 {
+		return
+	}
+
+	route := make([][]float64, len(track))
+	for i, point := range track {
+		route[i] = point.Location.coordinates()
+		if point.Title == "" {
+			continue
+		}
+
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "Point", Coordinates: point.Location.coordinates()},
+			Properties: geoJSONPlaceProperties{Kind: "place", Title: point.Title, Date: point.Date},
+		})
+	}
+
+	collection.Features = append(collection.Features, geoJSONFeature{
+		Type:       "Feature",
+		Geometry:   geoJSONGeometry{Type: "LineString", Coordinates: route},
+		Properties: geoJSONRouteProperties{Kind: "route"},
+	})
+}
+
+// handleAPIGetLocations returns the geotagged media of an album, or of all
+// albums if albumName is empty, as GeoJSON. The route of the album is
+// included when an album is requested.
+func (web *WebInterface) handleAPIGetLocations(w http.ResponseWriter, r *http.Request, albumName string) {
+	collection := newGeoJSONFeatureCollection()
+	if albumName != "" {
+		album, ok := web.getAPIAlbum(w, albumName)
+		if !ok {
+			return
+		}
+		collection.addAlbum(*album, GetBasePath(r))
+
+		track, err := web.MediaStore.GetTrack(album.ID)
+		if err != nil {
+			log.Printf("MediaStore.GetTrack: %s", err)
+			web.apiError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		collection.addTrack(track)
+	} else {
+		albums, err := web.MediaStore.ListGeotaggedMedia()
+		if err != nil {
+			log.Printf("MediaStore.ListGeotaggedMedia: %s", err)
+			web.apiError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		for _, album := range albums {
+			collection.addAlbum(album, GetBasePath(r))
+		}
+	}
+
+	web.apiResponse(w, collection, http.StatusOK)
+}
+
+// handleDisplayMap renders the map of an album, or of all albums if
+// albumName is empty. The points are loaded from the GeoJSON endpoint.
+func (web *WebInterface) handleDisplayMap(w http.ResponseWriter, r *http.Request, albumName string) {
+	title := web.I18n.Negotiate(r).SiteName
+	geoJSONURL := GetBasePath(r) + "/api/v1/locations"
+	if albumName != "" {
+		name := albumName
+		if name == "latest" {
+			name = ""
+		}
+
+		album, err := web.MediaStore.GetAlbum(name, true)
+		if errors.Is(err, ErrAlbumNotFound) {
+			web.handleFileNotFound(w, r)
+			return
+		} else if err != nil {
+			log.Printf("MediaStore.GetAlbum: %s", err)
+			web.handleError(w, r)
+			return
+		}
+
+		title = album.Title
+		geoJSONURL = GetBasePath(r) + "/api/v1/albums/" + url.PathEscape(albumName) + "/locations"
+	}
+
+	err := web.MapTemplate.Execute(w, struct {
+		I18n       I18n
+		Title      string
+		GeoJSONURL string
+		Map        MapSettings
+	}{
+		web.negotiateLanguage(w, r),
+		title,
+		geoJSONURL,
+		web.Map,
+	})
+	if err != nil {
+		log.Printf("Template.Execute: %s", err)
+		web.handleError(w, r)
+		return
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -8,6 +9,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
@@ -17,8 +19,24 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
+var ErrAlbumNotFound = errors.New("Unknown album")
+var ErrMediaNotFound = errors.New("Unknown media")
+
 type MediaStore struct {
 	StoreLocation string
+
+	// Media without GPS coordinates are geotagged using the locations shared
+	// in the chat within this duration
+	GeotagWindow time.Duration
+
+	// Serializes the updates of the album files (meta.yaml and chat.yaml)
+	lock sync.Mutex
+
+	// Albums loaded by the queries spanning all albums
+	cache albumCache
+
+	// When set, the most favorited photo is the default cover of the albums
+	Favorites *FavoritesDB
 }
 
 type Album struct {
@@ -30,11 +48,15 @@ type Album struct {
 }
 
 type Media struct {
-	Type    string    `yaml:"type"`
-	ID      string    `yaml:"id"`
-	Files   []string  `yaml:"-"` // Not part of the YAML struct
-	Caption string    `yaml:"caption"`
-	Date    time.Time `yaml:"date"`
+	Type     string    `yaml:"type"`
+	ID       string    `yaml:"id"`
+	Files    []string  `yaml:"-"` // Not part of the YAML struct
+	Caption  string    `yaml:"caption"`
+	Date     time.Time `yaml:"date"`
+	TakenAt  time.Time `yaml:"taken,omitempty"`
+	Uploader string    `yaml:"uploader,omitempty"`
+	Location *Location `yaml:"location,omitempty"`
+	Tags     []string  `yaml:"tags,omitempty"`
 }
 
 // A media without ID will not be serialized in YAML
@@ -42,6 +64,15 @@ func (m *Media) IsZero() bool {
 	return m.ID == ""
 }
 
+// TakenDate returns the date the media has been taken when it is known (EXIF),
+// the date it has been received otherwise.
+func (m *Media) TakenDate() time.Time {
+	if !m.TakenAt.IsZero() {
+		return m.TakenAt
+	}
+	return m.Date
+}
+
 func InitMediaStore(storeLocation string) (*MediaStore, error) {
 	err := os.MkdirAll(filepath.Join(storeLocation, ".current"), os.ModePerm)
 	if err != nil {
@@ -59,27 +90,57 @@ func (store *MediaStore) AddFile(fileName string) (*os.File, error) {
 	return os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
 }
 
-func (store *MediaStore) CommitPhoto(id string, timestamp time.Time, caption string) error {
-	return store.commitMedia(id, timestamp, caption, "photo")
+// DiscardFile removes a file added to the current album that will not be
+// committed
+func (store *MediaStore) DiscardFile(fileName string) error {
+	return os.Remove(filepath.Join(store.StoreLocation, ".current", fileName))
 }
 
-func (store *MediaStore) CommitVideo(id string, timestamp time.Time, caption string) error {
-	return store.commitMedia(id, timestamp, caption, "video")
+func (store *MediaStore) CommitPhoto(id string, timestamp time.Time, caption string, uploader string) error {
+	return store.commitMedia(id, timestamp, caption, uploader, "photo")
 }
 
-func (store *MediaStore) commitMedia(id string, timestamp time.Time, caption string, mediaType string) error {
+func (store *MediaStore) CommitVideo(id string, timestamp time.Time, caption string, uploader string) error {
+	return store.commitMedia(id, timestamp, caption, uploader, "video")
+}
+
+func (store *MediaStore) commitMedia(id string, timestamp time.Time, caption string, uploader string, mediaType string) error {
 	entry := [1]Media{{
-		Type:    mediaType,
-		Date:    timestamp,
-		Caption: caption,
-		ID:      id,
+		Type:     mediaType,
+		Date:     timestamp,
+		Caption:  caption,
+		ID:       id,
+		Uploader: uploader,
+		Tags:     parseHashtags(caption),
 	}}
 
+	if mediaType == "photo" {
+		exif, err := readExifFromFile(filepath.Join(store.StoreLocation, ".current", id+".jpeg"))
+		if err == nil { // Best effort: most photos sent through Telegram have no EXIF data
+			entry[0].TakenAt, _ = exif.DateTaken()
+			if location, ok := exif.Location(); ok {
+				entry[0].Location = &location
+			}
+		}
+	}
+
+	if entry[0].Location == nil && store.GeotagWindow > 0 {
+		track, err := store.GetTrack("")
+		if err != nil {
+			log.Printf("MediaStore.GetTrack: %s", err)
+		} else if location, ok := geotag(track, entry[0].Date, store.GeotagWindow); ok {
+			entry[0].Location = &location
+		}
+	}
+
 	yamlData, err := yaml.Marshal(entry)
 	if err != nil {
 		return err
 	}
 
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
 	return appendToFile(filepath.Join(store.StoreLocation, ".current", "chat.yaml"), yamlData)
 }
 
@@ -128,6 +189,68 @@ func (store *MediaStore) ListAlbums() (AlbumList, error) {
 	return albums, nil
 }
 
+// ListAlbumsUpdatedSince returns the albums having media newer than the given
+// date. Only those new media are part of the returned albums.
+func (store *MediaStore) ListAlbumsUpdatedSince(since time.Time) (AlbumList, error) {
+	return store.filterAlbums(func(media Media) bool {
+		return media.Date.After(since)
+	})
+}
+
+// ListMediaOnThisDay returns the albums having media taken on the same
+// calendar day as the given date, in earlier years. Only those media are part
+// of the returned albums.
+func (store *MediaStore) ListMediaOnThisDay(day time.Time) (AlbumList, error) {
+	return store.filterAlbums(func(media Media) bool {
+		taken := media.TakenDate().In(day.Location())
+		return taken.Year() < day.Year() && taken.Month() == day.Month() && taken.Day() == day.Day()
+	})
+}
+
+// ListGeotaggedMedia returns the albums having media with a location,
+// with only those media
+func (store *MediaStore) ListGeotaggedMedia() (AlbumList, error) {
+	return store.filterAlbums(func(media Media) bool {
+		return media.Location != nil
+	})
+}
+
+// filterAlbums returns the albums having at least one media matching the
+// filter. Only the matching media are part of the returned albums.
+func (store *MediaStore) filterAlbums(filter func(Media) bool) (AlbumList, error) {
+	files, err := ioutil.ReadDir(store.StoreLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	var albums AlbumList
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+
+		album, err := store.getCachedAlbum(file.Name())
+		if err != nil {
+			log.Printf("filterAlbums: Cannot extract album info for '%s'", file.Name())
+			continue
+		}
+
+		var matches []Media
+		for _, media := range album.Media {
+			if filter(media) {
+				matches = append(matches, media)
+			}
+		}
+
+		if len(matches) > 0 {
+			album.Media = matches
+			albums = append(albums, *album)
+		}
+	}
+
+	return albums, nil
+}
+
 func (store *MediaStore) OpenFile(albumName string, filename string) (*os.File, time.Time, error) {
 	if albumName == "" {
 		albumName = ".current"
@@ -158,7 +281,7 @@ func (store *MediaStore) GetAlbum(name string, metadataOnly bool) (*Album, error
 	}
 
 	if !fileExists(filepath.Join(store.StoreLocation, filename)) {
-		return nil, fmt.Errorf("Unknown album '%s'", name)
+		return nil, fmt.Errorf("%w '%s'", ErrAlbumNotFound, name)
 	}
 
 	err := store.fillAlbumMetadata(filename, &album)
@@ -187,7 +310,11 @@ func (store *MediaStore) GetAlbum(name string, metadataOnly bool) (*Album, error
 	}
 
 	if album.CoverMedia.IsZero() {
-		album.setDefaultCover()
+		var favorites map[string]int
+		if store.Favorites != nil {
+			favorites = store.Favorites.Counts()
+		}
+		album.setDefaultCover(favorites)
 	}
 
 	return &album, nil
@@ -265,9 +392,23 @@ func (store *MediaStore) GetCurrentAlbum() (*Album, error) {
 	return store.GetAlbum("", true)
 }
 
-func (album *Album) setDefaultCover() {
+// setDefaultCover chooses the most favorited photo as cover, or the first
+// photo if none has been favorited
+func (album *Album) setDefaultCover(favorites map[string]int) {
 	if len(album.Media) > 0 {
 		var cover Media
+		best := 0
+		for _, media := range album.Media {
+			if media.Type == "photo" && favorites[media.ID] > best {
+				cover = media
+				best = favorites[media.ID]
+			}
+		}
+		if !cover.IsZero() {
+			album.CoverMedia = cover
+			return
+		}
+
 		for _, media := range album.Media {
 			if media.Type == "photo" { // use the first photo of the album as cover media
 				cover = media
@@ -315,6 +456,16 @@ func (store *MediaStore) CloseAlbum() error {
 	return nil
 }
 
+// findFileWithSuffix returns the first file of a media having the given suffix
+func findFileWithSuffix(files []string, suffix string) string {
+	for _, file := range files {
+		if strings.HasSuffix(file, suffix) {
+			return file
+		}
+	}
+	return ""
+}
+
 func fileExists(filename string) bool {
 	_, err := os.Stat(filename)
 	return err == nil
@@ -353,12 +504,19 @@ func (store *MediaStore) NewAlbum(title string) error {
 	return nil
 }
 
-func sanitizeAlbumName(albumName string) string {
-	albumName = strings.ToLower(albumName)
+// foldText lowercases a text and removes its diacritics, so that "Élodie"
+// and "elodie" compare equal
+func foldText(text string) string {
+	text = strings.ToLower(text)
 	t := transform.Chain(norm.NFD, transform.RemoveFunc(func(r rune) bool {
 		return unicode.Is(unicode.Mn, r)
 	}), norm.NFC)
-	albumName, _, _ = transform.String(t, albumName)
+	text, _, _ = transform.String(t, text)
+	return text
+}
+
+func sanitizeAlbumName(albumName string) string {
+	albumName = foldText(albumName)
 
 	reg, err := regexp.Compile("\\s+")
 	if err != nil {
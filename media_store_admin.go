@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// errInvalidAdminAction is returned when an admin change is refused because
+// of its parameters
+var errInvalidAdminAction = errors.New("Invalid change")
+
+// albumFolder returns the folder of an album, the current album being ""
+func (store *MediaStore) albumFolder(albumName string) (string, error) {
+	folder := ".current"
+	if albumName != "" && albumName != ".current" {
+		folder = filepath.Base(albumName)
+	}
+
+	if !fileExists(filepath.Join(store.StoreLocation, folder)) {
+		return "", fmt.Errorf("%w '%s'", ErrAlbumNotFound, albumName)
+	}
+
+	return folder, nil
+}
+
+// updateAlbumMetadata applies a change to the meta.yaml of an album
+func (store *MediaStore) updateAlbumMetadata(albumName string, update func(album *Album) error) error {
+	folder, err := store.albumFolder(albumName)
+	if err != nil {
+		return err
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	var album Album
+	err = store.fillAlbumMetadata(folder, &album)
+	if err != nil {
+		return err
+	}
+
+	err = update(&album)
+	if err != nil {
+		return err
+	}
+
+	yamlData, err := yaml.Marshal(album)
+	if err != nil {
+		return err
+	}
+
+	return writeWithBackup(filepath.Join(store.StoreLocation, folder, "meta.yaml"), yamlData)
+}
+
+// updateAlbumContent applies a change to the list of media (chat.yaml) of
+// an album
+func (store *MediaStore) updateAlbumContent(albumName string, update func(media []Media) ([]Media, error)) error {
+	folder, err := store.albumFolder(albumName)
+	if err != nil {
+		return err
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	filename := filepath.Join(store.StoreLocation, folder, "chat.yaml")
+	yamlData, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var media []Media
+	err = yaml.UnmarshalStrict(yamlData, &media)
+	if err != nil {
+		return err
+	}
+
+	media, err = update(media)
+	if err != nil {
+		return err
+	}
+
+	yamlData, err = yaml.Marshal(media)
+	if err != nil {
+		return err
+	}
+
+	return writeWithBackup(filename, yamlData)
+}
+
+// writeWithBackup replaces the content of a file and keeps the previous one
+// as a backup (.bak). The new content is written to a temporary file renamed
+// over the original one, so that a crash never leaves a truncated file.
+func writeWithBackup(filename string, data []byte) error {
+	mode := os.FileMode(0644)
+	if stat, err := os.Stat(filename); err == nil {
+		mode = stat.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // in case of error
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = backupFile(filename)
+	if err != nil {
+		log.Printf("Cannot perform a backup of '%s' before update: %s", filename, err)
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// backupFile links (or copies if links are not supported) a file to its
+// backup (.bak), leaving the file in place
+func backupFile(filename string) error {
+	err := os.Remove(filename + ".bak")
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	err = os.Link(filename, filename+".bak")
+	if err == nil || os.IsNotExist(err) {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename+".bak", data, 0600)
+}
+
+func findMedia(media []Media, mediaId string) (int, error) {
+	for i := range media {
+		if media[i].ID == mediaId {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("%w '%s'", ErrMediaNotFound, mediaId)
+}
+
+// RenameAlbum changes the title of an album. The folder of the album is
+// kept as-is so that existing links continue to work.
+func (store *MediaStore) RenameAlbum(albumName string, title string) error {
+	if title == "" {
+		return fmt.Errorf("%w: the title of an album cannot be empty", errInvalidAdminAction)
+	}
+
+	return store.updateAlbumMetadata(albumName, func(album *Album) error {
+		album.Title = title
+		return nil
+	})
+}
+
+// SetCover chooses the cover media of an album
+func (store *MediaStore) SetCover(albumName string, mediaId string) error {
+	media, err := store.GetMedia(albumName, mediaId)
+	if err != nil {
+		return err
+	} else if media == nil {
+		return fmt.Errorf("%w '%s'", ErrMediaNotFound, mediaId)
+	}
+
+	return store.updateAlbumMetadata(albumName, func(album *Album) error {
+		album.CoverMedia = *media
+		return nil
+	})
+}
+
+// DeleteMedia removes a media, its comments and its files from an album.
+// The files are removed last, once the media is no longer referenced by the
+// album, so that a failure never leaves a media without its files.
+func (store *MediaStore) DeleteMedia(albumName string, mediaId string) error {
+	folder, err := store.albumFolder(albumName)
+	if err != nil {
+		return err
+	}
+
+	album, err := store.GetAlbum(albumName, false)
+	if err != nil {
+		return err
+	}
+	if _, err := findMedia(album.Media, mediaId); err != nil {
+		return err
+	}
+
+	// The default cover will be used instead. Done first since a reset cover
+	// is harmless if the rest fails.
+	err = store.updateAlbumMetadata(albumName, func(album *Album) error {
+		if album.CoverMedia.ID == mediaId {
+			album.CoverMedia = Media{}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = store.updateAlbumContent(albumName, func(media []Media) ([]Media, error) {
+		i, err := findMedia(media, mediaId)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(media[:i], media[i+1:]...), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = store.deleteComments(folder, mediaId)
+	if err != nil {
+		return err
+	}
+
+	files, err := filepath.Glob(filepath.Join(store.StoreLocation, folder, mediaId+".*"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		err = os.Remove(file)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MoveMedia changes the position of a media in an album. Positions out of
+// range are moved to the first or last position.
+func (store *MediaStore) MoveMedia(albumName string, mediaId string, position int) error {
+	return store.updateAlbumContent(albumName, func(media []Media) ([]Media, error) {
+		i, err := findMedia(media, mediaId)
+		if err != nil {
+			return nil, err
+		}
+
+		if position < 0 {
+			position = 0
+		} else if position >= len(media) {
+			position = len(media) - 1
+		}
+
+		moved := media[i]
+		media = append(media[:i], media[i+1:]...)
+		media = append(media[:position], append([]Media{moved}, media[position:]...)...)
+		return media, nil
+	})
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -48,7 +50,7 @@ func TestMediaStore(t *testing.T) {
 	fd1.WriteString("JPEG File")
 	fd1.Close()
 
-	err = store.CommitPhoto(id1, time.Now(), "This is a test")
+	err = store.CommitPhoto(id1, time.Now(), "This is a test", "Telegram:john")
 	if err != nil {
 		t.Errorf("CommitPhoto(): error %s", err)
 	}
@@ -68,7 +70,7 @@ func TestMediaStore(t *testing.T) {
 	fd3.WriteString("MP4 File")
 	fd3.Close()
 
-	err = store.CommitVideo(id2, time.Now(), "This is another test")
+	err = store.CommitVideo(id2, time.Now(), "This is another test", "Telegram:jane")
 	if err != nil {
 		t.Errorf("CommitVideo(): error %s", err)
 	}
@@ -81,6 +83,7 @@ func TestMediaStore(t *testing.T) {
 	assert.Equal(t, len(album.Media), 2, "current album has two media")
 	assert.Equal(t, len(album.Media[0].Files), 1, "current album, first media has one file")
 	assert.Equal(t, len(album.Media[1].Files), 2, "current album, second media has two files")
+	assert.Equal(t, album.Media[0].Uploader, "Telegram:john", "current album, first media has been sent by john")
 
 	now := time.Now()
 	err = store.NewAlbum("My album")
@@ -110,3 +113,127 @@ func TestMediaStore(t *testing.T) {
 	assert.Equal(t, albumList[0].ID, "", "album number one is the current album")
 	assert.Equal(t, albumList[1].ID, albumId, "album number two is 'My Album'")
 }
+
+func TestListMediaOnThisDay(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store, err := InitMediaStore(tmp.RootDir)
+	if err != nil {
+		t.Errorf("InitMediaStore(): error %s", err)
+	}
+
+	// A fixed day: AddDate would move Feb 29 to another day in earlier years
+	now := time.Date(2020, 6, 15, 12, 0, 0, 0, time.Local)
+	dates := []time.Time{now.AddDate(-2, 0, 0), now, now.AddDate(-1, 0, 1)}
+	for _, date := range dates {
+		err = store.CommitPhoto(store.GetUniqueID(), date, "", "")
+		if err != nil {
+			t.Errorf("CommitPhoto(): error %s", err)
+		}
+	}
+
+	albums, err := store.ListMediaOnThisDay(now)
+	if err != nil {
+		t.Errorf("ListMediaOnThisDay(): error %s", err)
+	}
+	assert.Equal(t, len(albums), 1, "one album has memories")
+	assert.Equal(t, len(albums[0].Media), 1, "only one media has been taken on this day in earlier years")
+	assert.Equal(t, albums[0].Media[0].Date.Equal(dates[0]), true, "the media taken two years ago")
+}
+
+func TestAlbumAdministration(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+	createTestAlbum(t, store, "My album")
+
+	ids := make([]string, 3)
+	for i := range ids {
+		ids[i] = addTestPhoto(t, store, time.Now(), "", "")
+	}
+
+	err := store.RenameAlbum("", "My renamed album")
+	if err != nil {
+		t.Errorf("RenameAlbum(): error %s", err)
+	}
+
+	err = store.SetCover("", ids[1])
+	if err != nil {
+		t.Errorf("SetCover(): error %s", err)
+	}
+
+	err = store.MoveMedia("", ids[2], 0)
+	if err != nil {
+		t.Errorf("MoveMedia(): error %s", err)
+	}
+
+	album, err := store.GetAlbum("", false)
+	if err != nil {
+		t.Errorf("GetAlbum(): error %s", err)
+	}
+	assert.Equal(t, album.Title, "My renamed album", "the album has been renamed")
+	assert.Equal(t, album.CoverMedia.ID, ids[1], "the cover has been changed")
+	assert.Equal(t, []string{album.Media[0].ID, album.Media[1].ID, album.Media[2].ID}, []string{ids[2], ids[0], ids[1]}, "the last media has been moved first")
+
+	err = store.AddComment(Comment{Media: ids[1], Author: "john", Source: "web", Text: "Nice"})
+	if err != nil {
+		t.Errorf("AddComment(): error %s", err)
+	}
+	err = store.AddComment(Comment{Media: ids[0], Author: "john", Source: "web", Text: "Great"})
+	if err != nil {
+		t.Errorf("AddComment(): error %s", err)
+	}
+
+	err = store.DeleteMedia("", ids[1])
+	if err != nil {
+		t.Errorf("DeleteMedia(): error %s", err)
+	}
+	comments, _ := store.GetComments("", ids[1])
+	assert.Equal(t, len(comments), 0, "the comments of the media have been deleted")
+	comments, _ = store.GetComments("", ids[0])
+	assert.Equal(t, len(comments), 1, "the comments of the other media are kept")
+
+	album, err = store.GetAlbum("", false)
+	if err != nil {
+		t.Errorf("GetAlbum(): error %s", err)
+	}
+	assert.Equal(t, len(album.Media), 2, "the media has been deleted")
+	assert.Equal(t, album.CoverMedia.ID, ids[2], "the default cover is used when the cover is deleted")
+	assert.Equal(t, fileExists(filepath.Join(tmp.RootDir, ".current", ids[1]+".jpeg")), false, "the files of the media have been deleted")
+
+	err = store.DeleteMedia("", ids[1])
+	assert.Equal(t, errors.Is(err, ErrMediaNotFound), true, "unknown media cannot be deleted")
+}
+
+func TestWriteWithBackup(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	file := filepath.Join(tmp.RootDir, "meta.yaml")
+	for _, content := range []string{"v1", "v2"} {
+		err := writeWithBackup(file, []byte(content))
+		if err != nil {
+			t.Fatalf("writeWithBackup(): error %s", err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(): error %s", err)
+	}
+	assert.Equal(t, string(data), "v2", "the file has the new content")
+
+	data, err = ioutil.ReadFile(file + ".bak")
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(): error %s", err)
+	}
+	assert.Equal(t, string(data), "v1", "the backup has the previous content")
+
+	files, err := ioutil.ReadDir(tmp.RootDir)
+	if err != nil {
+		t.Fatalf("ioutil.ReadDir(): error %s", err)
+	}
+	assert.Equal(t, len(files), 2, "no temporary file is left")
+}
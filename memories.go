@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/url"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+type memory struct {
+	AlbumID string
+	Media   Media
+}
+
+// SendMemories sends to each user who opted in a few media taken on the same
+// calendar day in earlier years.
+func (bot *TelegramBot) SendMemories(now time.Time) {
+	albums, err := bot.MediaStore.ListMediaOnThisDay(now)
+	if err != nil {
+		log.Printf("Cannot list the media taken on this day: %s", err)
+		return
+	}
+
+	var memories []memory
+	for _, album := range albums {
+		for _, media := range album.Media {
+			memories = append(memories, memory{AlbumID: album.ID, Media: media})
+		}
+	}
+	if len(memories) == 0 {
+		return
+	}
+
+	users := bot.Preferences.Users(func(p UserPreferences) bool {
+		return p.Memories
+	})
+	for _, username := range users {
+		err := bot.sendMemories(username, memories, now)
+		if err != nil {
+			log.Printf("[%s] cannot send memories: %s", username, err)
+		}
+	}
+}
+
+func (bot *TelegramBot) sendMemories(username string, memories []memory, now time.Time) error {
+	chatId, ok := bot.ChatDB.Lookup(username)
+	if !ok {
+		return fmt.Errorf("The chat db does not have any mapping for %s", username)
+	}
+
+	messages := bot.messagesForUser(username)
+
+	// Each user gets a different selection of media
+	selection := make([]memory, len(memories))
+	copy(selection, memories)
+	rand.Shuffle(len(selection), func(i, j int) {
+		selection[i], selection[j] = selection[j], selection[i]
+	})
+	if len(selection) > bot.MemoriesCount {
+		selection = selection[:bot.MemoriesCount]
+	}
+
+	_, err := bot.API.Send(tgbotapi.NewMessage(chatId, messages.Memories))
+	if err != nil {
+		return err
+	}
+
+	for _, m := range selection {
+		id := m.AlbumID
+		if id == "" {
+			id = "latest"
+		}
+		link := bot.getShareURL(username, id, now) + "media/" + url.PathEscape(m.Media.ID) + "/"
+		caption := fmt.Sprintf(messages.MemoryMedia, m.Media.TakenDate().Format("2006-01-02"), m.Media.Caption, link)
+		err = bot.sendPhotoWithCaption(chatId, m.AlbumID, m.Media.Files, caption)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
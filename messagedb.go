@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// MessageDB remembers which media has been sent in which Telegram message,
+// so that users can act on a media by replying to its message
+type MessageDB struct {
+	Path string
+
+	// Map "<chat id>/<message id>" to media id
+	Db map[string]string
+
+	lock sync.RWMutex
+}
+
+// A MessageRef identifies a Telegram message
+type MessageRef struct {
+	ChatID    int64
+	MessageID int
+}
+
+func (ref MessageRef) key() string {
+	return fmt.Sprintf("%d/%d", ref.ChatID, ref.MessageID)
+}
+
+func InitMessageDB(path string) (*MessageDB, error) {
+	db := make(map[string]string)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	yamlData, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(yamlData, &db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MessageDB{Path: path, Db: db}, nil
+}
+
+// Lookup returns the media sent in a message
+func (messagedb *MessageDB) Lookup(ref MessageRef) (string, bool) {
+	messagedb.lock.RLock()
+	defer messagedb.lock.RUnlock()
+
+	mediaId, ok := messagedb.Db[ref.key()]
+	return mediaId, ok
+}
+
+// Record remembers the messages a media has been sent in
+func (messagedb *MessageDB) Record(mediaId string, refs ...MessageRef) error {
+	messagedb.lock.Lock()
+	defer messagedb.lock.Unlock()
+
+	for _, ref := range refs {
+		messagedb.Db[ref.key()] = mediaId
+	}
+
+	yamlData, err := yaml.Marshal(messagedb.Db)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(messagedb.Path, messagedb.Path+".bak")
+	if err != nil {
+		log.Printf("Cannot perform a backup of the messagedb before update: %s", err)
+	}
+
+	return ioutil.WriteFile(messagedb.Path, yamlData, 0600)
+}
+
+// Forget removes the messages of a deleted media
+func (messagedb *MessageDB) Forget(mediaId string) error {
+	messagedb.lock.Lock()
+	defer messagedb.lock.Unlock()
+
+	changed := false
+	for key, id := range messagedb.Db {
+		if id == mediaId {
+			delete(messagedb.Db, key)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	yamlData, err := yaml.Marshal(messagedb.Db)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(messagedb.Path, messagedb.Path+".bak")
+	if err != nil {
+		log.Printf("Cannot perform a backup of the messagedb before update: %s", err)
+	}
+
+	return ioutil.WriteFile(messagedb.Path, yamlData, 0600)
+}
+
+// Find returns the message of a chat a media has been sent in
+func (messagedb *MessageDB) Find(chatId int64, mediaId string) (int, bool) {
+	messagedb.lock.RLock()
+	defer messagedb.lock.RUnlock()
+
+	prefix := fmt.Sprintf("%d/", chatId)
+	for key, id := range messagedb.Db {
+		if id != mediaId || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		messageId, err := strconv.Atoi(strings.TrimPrefix(key, prefix))
+		if err == nil {
+			return messageId, true
+		}
+	}
+
+	return 0, false
+}
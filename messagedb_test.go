@@ -0,0 +1,57 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestMessageDB(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	file := filepath.Join(tmp.RootDir, "messages.yaml")
+	messagedb, err := InitMessageDB(file)
+	if err != nil {
+		t.Fatalf("InitMessageDB(): %s", err)
+	}
+
+	err = messagedb.Record("media-1", MessageRef{ChatID: 123, MessageID: 1}, MessageRef{ChatID: 456, MessageID: 7})
+	if err != nil {
+		t.Fatalf("Record(): %s", err)
+	}
+
+	// Reload the db from the disk
+	messagedb, err = InitMessageDB(file)
+	if err != nil {
+		t.Fatalf("InitMessageDB(): %s", err)
+	}
+
+	mediaId, ok := messagedb.Lookup(MessageRef{ChatID: 456, MessageID: 7})
+	assert.Equal(t, ok, true, "the forwarded message is known")
+	assert.Equal(t, mediaId, "media-1", "the forwarded message maps to the media")
+
+	_, ok = messagedb.Lookup(MessageRef{ChatID: 456, MessageID: 1})
+	assert.Equal(t, ok, false, "message ids are per chat")
+}
+
+func TestMessageDBFind(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	messagedb, err := InitMessageDB(filepath.Join(tmp.RootDir, "messages.yaml"))
+	if err != nil {
+		t.Fatalf("InitMessageDB(): %s", err)
+	}
+
+	messagedb.Record("media-1", MessageRef{ChatID: 123, MessageID: 1}, MessageRef{ChatID: 456, MessageID: 7})
+	messagedb.Record("media-2", MessageRef{ChatID: 456, MessageID: 8})
+
+	messageId, ok := messagedb.Find(456, "media-1")
+	assert.Equal(t, ok, true, "the media has been sent in this chat")
+	assert.Equal(t, messageId, 7, "the message of the media")
+
+	_, ok = messagedb.Find(123, "media-2")
+	assert.Equal(t, ok, false, "the media has not been sent in this chat")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+)
+
+var errUnknownCursor = errors.New("Unknown cursor")
+
+// A Page is a part of the media of an album. Cursors are the ids of the
+// first and last media of the page, empty when there is no previous or next
+// page.
+type Page struct {
+	Media  []Media
+	Before string // cursor of the previous page (?before=)
+	After  string // cursor of the next page (?after=)
+}
+
+// paginate returns the media following the "after" cursor or preceding the
+// "before" cursor, in the order of the album. Cursors are media ids so that
+// pages stay consistent when media are added to the album.
+func paginate(media []Media, after string, before string, size int) (Page, error) {
+	start, end := 0, len(media)
+	if after != "" {
+		i, err := findMedia(media, after)
+		if err != nil {
+			return Page{}, errUnknownCursor
+		}
+		start = i + 1
+		end = start + size
+	} else if before != "" {
+		i, err := findMedia(media, before)
+		if err != nil {
+			return Page{}, errUnknownCursor
+		}
+		end = i
+		start = end - size
+	} else {
+		end = size
+	}
+
+	if start < 0 {
+		start = 0
+	}
+	if end > len(media) {
+		end = len(media)
+	}
+
+	page := Page{Media: media[start:end]}
+	if start > 0 && start < end {
+		page.Before = media[start].ID
+	}
+	if end < len(media) && start < end {
+		page.After = media[end-1].ID
+	}
+
+	return page, nil
+}
+
+// getAlbumPage returns the page of the album requested with the "after" or
+// "before" query parameters
+func (web *WebInterface) getAlbumPage(album *Album, r *http.Request) (Page, error) {
+	query := r.URL.Query()
+	return paginate(album.Media, query.Get("after"), query.Get("before"), web.PageSize)
+}
+
+type albumFragment struct {
+	HTML  string `json:"html"`
+	After string `json:"after,omitempty"`
+}
+
+// handleAlbumFragment returns the HTML of a page of the album, to be appended
+// to the album page (infinite scroll).
+func (web *WebInterface) handleAlbumFragment(w http.ResponseWriter, r *http.Request, albumName string) {
+	album, ok := web.getAPIAlbum(w, albumName)
+	if !ok {
+		return
+	}
+
+	web.filterAlbum(album, r)
+	page, err := web.getAlbumPage(album, r)
+	if err != nil {
+		web.apiError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var buffer bytes.Buffer
+	err = web.AlbumTemplate.ExecuteTemplate(&buffer, "media-items", page.Media)
+	if err != nil {
+		log.Printf("Template.Execute: %s", err)
+		web.apiError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	web.apiResponse(w, albumFragment{HTML: buffer.String(), After: page.After}, http.StatusOK)
+}
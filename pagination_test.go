@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestPaginate(t *testing.T) {
+	media := make([]Media, 5)
+	for i := range media {
+		media[i].ID = fmt.Sprintf("%d", i)
+	}
+
+	ids := func(page Page) []string {
+		result := []string{}
+		for _, m := range page.Media {
+			result = append(result, m.ID)
+		}
+		return result
+	}
+
+	page, err := paginate(media, "", "", 2)
+	assert.Equal(t, err, nil, "first page")
+	assert.Equal(t, ids(page), []string{"0", "1"}, "first page content")
+	assert.Equal(t, page.Before, "", "no page before the first one")
+	assert.Equal(t, page.After, "1", "cursor of the second page")
+
+	page, err = paginate(media, page.After, "", 2)
+	assert.Equal(t, err, nil, "second page")
+	assert.Equal(t, ids(page), []string{"2", "3"}, "second page content")
+	assert.Equal(t, page.Before, "2", "cursor of the first page")
+	assert.Equal(t, page.After, "3", "cursor of the last page")
+
+	page, err = paginate(media, page.After, "", 2)
+	assert.Equal(t, err, nil, "last page")
+	assert.Equal(t, ids(page), []string{"4"}, "last page content")
+	assert.Equal(t, page.After, "", "no page after the last one")
+
+	page, err = paginate(media, "", page.Before, 2)
+	assert.Equal(t, err, nil, "previous page")
+	assert.Equal(t, ids(page), []string{"2", "3"}, "previous page content")
+
+	page, err = paginate(media, "", "1", 2)
+	assert.Equal(t, err, nil, "truncated previous page")
+	assert.Equal(t, ids(page), []string{"0"}, "truncated previous page content")
+	assert.Equal(t, page.Before, "", "no page before the first one")
+
+	_, err = paginate(media, "unknown", "", 2)
+	assert.Equal(t, err, errUnknownCursor, "unknown cursor")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+type UserPreferences struct {
+	Digest     bool      `yaml:"digest,omitempty"`
+	LastDigest time.Time `yaml:"lastDigest,omitempty"`
+	Memories   bool      `yaml:"memories,omitempty"`
+
+	// Language chosen by the user and language of its Telegram client
+	Language     string `yaml:"language,omitempty"`
+	LanguageCode string `yaml:"languageCode,omitempty"`
+}
+
+type PreferencesDB struct {
+	Path string
+
+	// Map usernames to their preferences
+	Db map[string]UserPreferences
+
+	lock sync.Mutex
+}
+
+func InitPreferencesDB(path string) (*PreferencesDB, error) {
+	db := make(map[string]UserPreferences)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	yamlData, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(yamlData, &db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreferencesDB{Path: path, Db: db}, nil
+}
+
+func (prefs *PreferencesDB) Get(username string) UserPreferences {
+	prefs.lock.Lock()
+	defer prefs.lock.Unlock()
+
+	return prefs.Db[username]
+}
+
+// Users returns the usernames whose preferences match the given filter
+func (prefs *PreferencesDB) Users(filter func(UserPreferences) bool) []string {
+	prefs.lock.Lock()
+	defer prefs.lock.Unlock()
+
+	var users []string
+	for username, p := range prefs.Db {
+		if filter(p) {
+			users = append(users, username)
+		}
+	}
+
+	return users
+}
+
+// Update applies the given function to the preferences of a user and
+// persists the result.
+func (prefs *PreferencesDB) Update(username string, update func(*UserPreferences)) error {
+	prefs.lock.Lock()
+	defer prefs.lock.Unlock()
+
+	p := prefs.Db[username]
+	update(&p)
+	prefs.Db[username] = p
+
+	yamlData, err := yaml.Marshal(prefs.Db)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(prefs.Path, prefs.Path+".bak")
+	if err != nil {
+		log.Printf("Cannot perform a backup of the preferences db before update: %s", err)
+	}
+
+	return ioutil.WriteFile(prefs.Path, yamlData, 0600)
+}
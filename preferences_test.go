@@ -0,0 +1,39 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestPreferencesDB(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	file := filepath.Join(tmp.RootDir, "preferences.yaml")
+
+	prefs, err := InitPreferencesDB(file)
+	if err != nil {
+		t.Errorf("InitPreferencesDB(): %s", err)
+	}
+
+	now := time.Unix(1588703522, 0)
+	err = prefs.Update("john", func(p *UserPreferences) {
+		p.Digest = true
+		p.LastDigest = now
+	})
+	if err != nil {
+		t.Errorf("Update(): %s", err)
+	}
+
+	prefs, err = InitPreferencesDB(file)
+	if err != nil {
+		t.Errorf("InitPreferencesDB(): %s", err)
+	}
+	assert.Equal(t, prefs.Get("john").Digest, true, "john subscribed to the digest")
+	assert.Equal(t, prefs.Get("john").LastDigest.Equal(now), true, "last digest date is persisted")
+	assert.Equal(t, prefs.Get("jane").Digest, false, "jane did not subscribe")
+	assert.Equal(t, prefs.Users(func(p UserPreferences) bool { return p.Digest }), []string{"john"}, "digest subscribers")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A LinkPreview holds the OpenGraph metadata of a page, shown by messaging
+// apps when a link is pasted
+type LinkPreview struct {
+	Title       string
+	Description string
+	Image       string // absolute and signed URL, crawlers have no session
+}
+
+// previewImageURL returns a signed URL giving access to a single file for
+// a short time, or an empty string if URLs cannot be signed
+func (web *WebInterface) previewImageURL(albumId string, filename string) string {
+	if web.TokenGenerator == nil || filename == "" {
+		return ""
+	}
+
+	if albumId == "" {
+		albumId = "latest"
+	}
+
+	// The signature covers the path as seen by the SecurityFrontend
+	p := "/album/" + albumId + "/raw/" + filename
+	expires := time.Now().Add(web.PreviewValidity).Unix()
+	signature := web.TokenGenerator.SignPath(p, time.Unix(expires, 0))
+
+	return strings.TrimSuffix(web.PublicURL, "/") + "/preview/" + strconv.FormatInt(expires, 10) + "/" + url.PathEscape(signature) +
+		"/album/" + url.PathEscape(albumId) + "/raw/" + url.PathEscape(filename)
+}
+
+func (web *WebInterface) albumPreview(album *Album, i18n I18n) LinkPreview {
+	return LinkPreview{
+		Title:       album.Title,
+		Description: i18n.FormatDate(album.Date),
+		Image:       web.previewImageURL(album.ID, findFileWithSuffix(album.CoverMedia.Files, ".jpeg")),
+	}
+}
+
+func (web *WebInterface) mediaPreview(album *Album, media *Media, i18n I18n) LinkPreview {
+	preview := LinkPreview{
+		Title:       media.Caption,
+		Description: album.Title,
+		Image:       web.previewImageURL(album.ID, findFileWithSuffix(media.Files, ".jpeg")),
+	}
+	if preview.Title == "" {
+		preview.Title = album.Title
+		preview.Description = i18n.FormatDate(media.Date)
+	}
+
+	return preview
+}
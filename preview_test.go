@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestPreviewImageURL(t *testing.T) {
+	g, err := NewTokenGenerator([]byte("secret"), crypto.SHA256)
+	if err != nil {
+		t.Fatalf("NewTokenGenerator(): %s", err)
+	}
+
+	web := &WebInterface{PublicURL: "https://photos.example.test/", TokenGenerator: g, PreviewValidity: time.Hour}
+	link := web.previewImageURL("", "1234.jpeg")
+	assert.Equal(t, strings.HasPrefix(link, "https://photos.example.test/preview/"), true, "absolute URL")
+	assert.Equal(t, strings.HasSuffix(link, "/album/latest/raw/1234.jpeg"), true, "URL of the file")
+
+	var served *http.Request
+	securityFrontend := &SecurityFrontend{
+		TokenGenerator: g,
+		Protected: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			served = r
+		}),
+	}
+	get := func(target string) int {
+		served = nil
+		w := httptest.NewRecorder()
+		securityFrontend.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w.Code
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("url.Parse(): %s", err)
+	}
+	assert.Equal(t, get(u.Path), http.StatusOK, "signed URL")
+	assert.Equal(t, served.URL.Path, "/album/latest/raw/1234.jpeg", "the file is served")
+	assert.Equal(t, GetWebUser(served).Type, TypePreview, "link preview user")
+
+	assert.Equal(t, get(strings.Replace(u.Path, "1234.jpeg", "5678.jpeg", 1)), http.StatusBadRequest, "the URL only gives access to this file")
+	assert.Equal(t, get(strings.Replace(u.Path, "/raw/1234.jpeg", "/", 1)), http.StatusBadRequest, "the URL only gives access to this file")
+
+	expired := time.Now().Add(-time.Minute)
+	p := "/album/latest/raw/1234.jpeg"
+	assert.Equal(t, g.ValidatePath(p, expired, g.SignPath(p, expired)), false, "signatures expire")
+}
+
+func TestMediaPreview(t *testing.T) {
+	web := &WebInterface{}
+	i18n := I18n{DateFormat: "2006-01"}
+	album := &Album{Title: "Holidays"}
+	date := time.Date(2020, 7, 14, 12, 0, 0, 0, time.UTC)
+
+	preview := web.mediaPreview(album, &Media{Caption: "Beach", Date: date}, i18n)
+	assert.Equal(t, preview, LinkPreview{Title: "Beach", Description: "Holidays"}, "caption as title")
+
+	preview = web.mediaPreview(album, &Media{Date: date}, i18n)
+	assert.Equal(t, preview, LinkPreview{Title: "Holidays", Description: "2020-07"}, "album title without caption")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// A ScheduledJob runs every day (or every week, on a given weekday) at a
+// fixed time of day, in the local timezone.
+type ScheduledJob struct {
+	Name    string
+	Hour    int
+	Minute  int
+	Weekly  bool
+	Weekday time.Weekday
+	Run     func(now time.Time)
+}
+
+type Scheduler struct {
+	Jobs []ScheduledJob
+}
+
+func (job ScheduledJob) NextRun(after time.Time) time.Time {
+	y, m, d := after.Date()
+	next := time.Date(y, m, d, job.Hour, job.Minute, 0, 0, after.Location())
+	for !next.After(after) || (job.Weekly && next.Weekday() != job.Weekday) {
+		next = time.Date(next.Year(), next.Month(), next.Day()+1, job.Hour, job.Minute, 0, 0, after.Location())
+	}
+	return next
+}
+
+func (scheduler *Scheduler) Add(job ScheduledJob) {
+	scheduler.Jobs = append(scheduler.Jobs, job)
+}
+
+func (scheduler *Scheduler) Start() {
+	for _, job := range scheduler.Jobs {
+		go scheduler.loop(job)
+	}
+}
+
+func (scheduler *Scheduler) loop(job ScheduledJob) {
+	for {
+		next := job.NextRun(time.Now())
+		log.Printf("Scheduler: next run of '%s' at %s", job.Name, next.Format(time.RFC3339))
+		time.Sleep(time.Until(next))
+		job.Run(time.Now())
+	}
+}
+
+// parseTimeOfDay parses a "HH:MM" string
+func parseTimeOfDay(s string) (int, int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid time of day '%s': %s", s, err)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), s) {
+			return day, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("Invalid weekday '%s'", s)
+}
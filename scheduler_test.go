@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestNextRun(t *testing.T) {
+	job := ScheduledJob{Hour: 19, Minute: 30}
+	now := time.Date(2020, 5, 6, 10, 0, 0, 0, time.UTC) // a Wednesday
+	assert.Equal(t, job.NextRun(now), time.Date(2020, 5, 6, 19, 30, 0, 0, time.UTC), "daily job runs later the same day")
+
+	now = time.Date(2020, 5, 6, 19, 30, 0, 0, time.UTC)
+	assert.Equal(t, job.NextRun(now), time.Date(2020, 5, 7, 19, 30, 0, 0, time.UTC), "daily job runs the next day")
+
+	job.Weekly = true
+	job.Weekday = time.Sunday
+	assert.Equal(t, job.NextRun(now), time.Date(2020, 5, 10, 19, 30, 0, 0, time.UTC), "weekly job runs next sunday")
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	hour, minute, err := parseTimeOfDay("07:45")
+	if err != nil {
+		t.Errorf("parseTimeOfDay(): %s", err)
+	}
+	assert.Equal(t, hour, 7, "hour")
+	assert.Equal(t, minute, 45, "minute")
+
+	_, _, err = parseTimeOfDay("25:00")
+	if err == nil {
+		t.Errorf("parseTimeOfDay(): invalid time accepted")
+	}
+
+	day, err := parseWeekday("sunday")
+	if err != nil {
+		t.Errorf("parseWeekday(): %s", err)
+	}
+	assert.Equal(t, day, time.Sunday, "weekday")
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// SearchResults are the albums whose title matches a query and the media
+// whose caption, tags (or album title) match it, grouped by album
+type SearchResults struct {
+	Albums AlbumList
+	Media  AlbumList
+}
+
+// MediaCount returns the number of media found
+func (results SearchResults) MediaCount() int {
+	count := 0
+	for _, album := range results.Media {
+		count += len(album.Media)
+	}
+	return count
+}
+
+// searchTerms splits a query in words, without diacritics nor punctuation
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(foldText(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchesAll returns true if the (folded) text contains all the terms
+func matchesAll(text string, terms []string) bool {
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// mediaSearchText returns the text a media is searched by
+func mediaSearchText(albumTitle string, media Media) string {
+	return foldText(albumTitle + " " + media.Caption + " " + strings.Join(media.Tags, " "))
+}
+
+// Search returns the albums and media matching all the words of the query,
+// ignoring case and diacritics. Most recent albums come first.
+func (store *MediaStore) Search(query string) (SearchResults, error) {
+	var results SearchResults
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	files, err := ioutil.ReadDir(store.StoreLocation)
+	if err != nil {
+		return results, err
+	}
+
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+
+		// The text of the albums and media is folded once, when cached
+		entry, err := store.getCacheEntry(file.Name())
+		if err != nil {
+			log.Printf("Search: Cannot extract album info for '%s'", file.Name())
+			continue
+		}
+
+		var matches []Media
+		for i, text := range entry.mediaText {
+			if matchesAll(text, terms) {
+				matches = append(matches, entry.album.Media[i])
+			}
+		}
+
+		album := entry.album
+		if matchesAll(entry.titleText, terms) {
+			album.Media = nil
+			results.Albums = append(results.Albums, album)
+		}
+		if len(matches) > 0 {
+			album.Media = matches
+			results.Media = append(results.Media, album)
+		}
+	}
+
+	sort.Sort(sort.Reverse(results.Albums))
+	sort.Sort(sort.Reverse(results.Media))
+	return results, nil
+}
+
+func (web *WebInterface) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	results, err := web.MediaStore.Search(query)
+	if err != nil {
+		log.Printf("MediaStore.Search: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	err = web.SearchTemplate.Execute(w, struct {
+		I18n    I18n
+		Query   string
+		Results SearchResults
+	}{
+		web.negotiateLanguage(w, r),
+		query,
+		results,
+	})
+	if err != nil {
+		log.Printf("Template.Execute: %s", err)
+		web.handleError(w, r)
+		return
+	}
+}
+
+type apiSearchResults struct {
+	Albums []apiAlbum `json:"albums"`
+	Media  []apiMedia `json:"media"`
+}
+
+func (web *WebInterface) handleAPISearch(w http.ResponseWriter, r *http.Request, baseURL string) {
+	query := r.URL.Query().Get("q")
+	if len(searchTerms(query)) == 0 {
+		web.apiError(w, "Missing query", http.StatusBadRequest)
+		return
+	}
+
+	results, err := web.MediaStore.Search(query)
+	if err != nil {
+		log.Printf("MediaStore.Search: %s", err)
+		web.apiError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := apiSearchResults{Albums: []apiAlbum{}, Media: []apiMedia{}}
+	for _, album := range results.Albums {
+		response.Albums = append(response.Albums, newAPIAlbum(album, baseURL))
+	}
+	for _, album := range results.Media {
+		albumURL := newAPIAlbum(album, baseURL).URL
+		for _, media := range album.Media {
+			response.Media = append(response.Media, newAPIMedia(media, albumURL))
+		}
+	}
+
+	web.apiResponse(w, response, http.StatusOK)
+}
+
+// Number of media sent in reply to the /search command
+const searchResultsCount = 5
+
+func (bot *TelegramBot) handleSearchCommand(message *tgbotapi.Message) {
+	messages := bot.messagesFor(message)
+	username := message.From.UserName
+
+	query := message.CommandArguments()
+	if len(searchTerms(query)) == 0 {
+		bot.replyToCommandWithMessage(message, messages.SearchUsage)
+		return
+	}
+
+	results, err := bot.MediaStore.Search(query)
+	if err != nil {
+		log.Printf("[%s] cannot search '%s': %s", username, query, err)
+		bot.replyToCommandWithMessage(message, messages.ServerError)
+		return
+	}
+
+	count := results.MediaCount()
+	if count == 0 {
+		bot.replyWithMessage(message, messages.SearchNoResult)
+		return
+	}
+
+	// All results can be browsed from the web interface
+	now := time.Now()
+	link := bot.getShareURL(username, "", now) + "search/?q=" + url.QueryEscape(query)
+	bot.replyWithMessage(message, fmt.Sprintf(messages.SearchResults, count, link))
+
+	sent := 0
+	for _, album := range results.Media {
+		id := album.ID
+		if id == "" {
+			id = "latest"
+		}
+
+		for _, media := range album.Media {
+			if sent >= searchResultsCount {
+				return
+			}
+
+			link := bot.getShareURL(username, id, now) + "media/" + url.PathEscape(media.ID) + "/"
+			caption := fmt.Sprintf(messages.SearchMedia, media.Caption, link)
+			err := bot.sendPhotoWithCaption(message.Chat.ID, album.ID, media.Files, caption)
+			if err != nil {
+				log.Printf("[%s] cannot send search result: %s", username, err)
+				return
+			}
+			sent++
+		}
+	}
+}
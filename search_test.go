@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, searchTerms("Grandpa's  BOAT!"), []string{"grandpa", "s", "boat"}, "punctuation is ignored")
+	assert.Equal(t, searchTerms("Élodie à la plage"), []string{"elodie", "a", "la", "plage"}, "diacritics are removed")
+	assert.Equal(t, len(searchTerms("  ?! ")), 0, "no words")
+}
+
+func TestSearch(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+
+	createTestAlbum(t, store, "Été chez Papi")
+	boat := addTestPhoto(t, store, time.Now(), "Le bateau de papi", "")
+	addTestPhoto(t, store, time.Now(), "Un château de sable", "")
+
+	results, err := store.Search("BATEAU")
+	if err != nil {
+		t.Fatalf("Search(): error %s", err)
+	}
+	assert.Equal(t, results.MediaCount(), 1, "one media matches")
+	assert.Equal(t, results.Media[0].Media[0].ID, boat, "the caption matches")
+	assert.Equal(t, len(results.Albums), 0, "no album title matches")
+
+	results, err = store.Search("ete chateau")
+	if err != nil {
+		t.Fatalf("Search(): error %s", err)
+	}
+	assert.Equal(t, results.MediaCount(), 1, "the words can be found in the album title and the caption")
+	assert.Equal(t, len(results.Albums), 0, "the album title does not have all the words")
+
+	results, err = store.Search("papi")
+	if err != nil {
+		t.Fatalf("Search(): error %s", err)
+	}
+	assert.Equal(t, len(results.Albums), 1, "the album title matches")
+	assert.Equal(t, results.MediaCount(), 2, "all media of the album match")
+
+	web := &WebInterface{MediaStore: store}
+	r := httptest.NewRequest("GET", "/api/v1/search/?q=bateau", nil)
+	w := httptest.NewRecorder()
+	web.ServeHTTP(w, withWebUser(r, &WebUser{Username: "john", Type: TypeTelegramUser, Scopes: []string{ScopeRead}}, ""))
+	assert.Equal(t, w.Code, http.StatusOK, "search through the API")
+
+	var response apiSearchResults
+	err = json.NewDecoder(w.Body).Decode(&response)
+	if err != nil {
+		t.Fatalf("json.Decode(): error %s", err)
+	}
+	assert.Equal(t, len(response.Media), 1, "the API returns the matching media")
+	assert.Equal(t, response.Media[0].URL, "/api/v1/albums/latest/media/"+boat+"/", "the media URL targets its album")
+}
@@ -2,16 +2,20 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/gob"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/coreos/go-oidc"
 	"github.com/gorilla/sessions"
+	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/oauth2"
 )
 
@@ -21,6 +25,10 @@ type SecurityFrontend struct {
 	TokenGenerator        *TokenGenerator
 	GlobalTokenValidity   int
 	PerAlbumTokenValidity int
+	APITokens             *APITokenStore
+	PublicURL             string
+	Admins                map[string]bool   // OIDC users having the admin scope
+	WebDAVUsers           map[string]string // bcrypt password hashes of the WebDAV users
 
 	store        *sessions.CookieStore
 	oAuth2Config *oauth2.Config
@@ -86,6 +94,8 @@ func NewSecurityFrontend(openidSettings OpenIdSettings, sessionSettings SessionS
 		MaxAge:   sessionSettings.CookieMaxAge,
 		HttpOnly: true,
 		Secure:   sessionSettings.SecureCookie,
+		// Protects the upload form from cross-site requests
+		SameSite: http.SameSiteLaxMode,
 	}
 
 	securityFrontend.OpenId = openidSettings
@@ -96,6 +106,7 @@ func NewSecurityFrontend(openidSettings OpenIdSettings, sessionSettings SessionS
 
 func (securityFrontend *SecurityFrontend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	originalPath := r.URL.Path
+	interactive := true
 	if r.URL.Path == "/oauth/callback" {
 		if r.Method != "GET" {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -106,27 +117,98 @@ func (securityFrontend *SecurityFrontend) ServeHTTP(w http.ResponseWriter, r *ht
 		return
 	}
 
+	if r.URL.Path == "/oembed" {
+		// The oEmbed endpoint describes the album behind the link given as
+		// parameter. It is served as if requested through this link, so that
+		// the share token (if any) is validated.
+		target, ok := oEmbedTarget(r.URL.Query().Get("url"), securityFrontend.PublicURL)
+		if !ok {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		r.URL.Path, r.URL.RawPath = target, ""
+		originalPath = target
+
+		// oEmbed consumers cannot follow the login redirect
+		interactive = false
+	}
+
 	head, tail := ShiftPath(r.URL.Path)
 	var user *WebUser
-	if head == "s" {
+	if bearer, ok := getBearerToken(r); ok && (head == "album" || head == "api") {
+		user, ok = securityFrontend.handleBearerAuthentication(w, r, bearer)
+		if !ok {
+			return
+		}
+	} else if head == "s" {
 		var ok bool
 		r.URL.Path = tail
 		user, ok = securityFrontend.handleTelegramTokenAuthentication(w, r)
 		if !ok {
 			return
 		}
+	} else if head == "webdav" {
+		var ok bool
+		user, ok = securityFrontend.handleWebDAVAuthentication(w, r)
+		if !ok {
+			return
+		}
+	} else if head == "preview" {
+		var ok bool
+		r.URL.Path = tail
+		user, ok = securityFrontend.handlePreviewAuthentication(w, r)
+		if !ok {
+			return
+		}
 	} else if head == "album" {
 		var ok bool
-		user, ok = securityFrontend.handleOidcAuthentication(w, r)
+		user, ok = securityFrontend.handleOidcAuthentication(w, r, interactive)
+		if !ok {
+			return
+		}
+	} else if head == "api" {
+		var ok bool
+		user, ok = securityFrontend.handleOidcAuthentication(w, r, false)
 		if !ok {
 			return
 		}
+	} else if head == "admin" {
+		var ok bool
+		user, ok = securityFrontend.handleOidcAuthentication(w, r, true)
+		if !ok {
+			return
+		}
+
+		if !user.HasScope(ScopeAdmin) {
+			log.Printf("[%s] %s %s: not an admin", user, r.Method, r.URL.Path)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		var csrfToken string
+		csrfToken, ok = securityFrontend.handleCSRFProtection(w, r)
+		if !ok {
+			return
+		}
+		r = withCSRFToken(r, csrfToken)
 	} else {
 		user = &WebUser{}
 	}
 
 	log.Printf("[%s] %s %s", user, r.Method, r.URL.Path)
 
+	if (r.Method == "GET" || r.Method == "HEAD") && user.Type != TypeAnonymous && !user.HasScope(ScopeRead) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// The prefix stripped from the URL (if any) is the base path of the web interface
+	basePath := strings.TrimSuffix(path.Clean(originalPath), r.URL.Path)
+	if basePath == "/" {
+		basePath = ""
+	}
+	r = withWebUser(r, user, basePath)
+
 	// Respect the user's choice about trailing slash
 	if strings.HasSuffix(originalPath, "/") && !strings.HasSuffix(r.URL.Path, "/") {
 		r.URL.Path = r.URL.Path + "/"
@@ -252,6 +334,7 @@ func (securityFrontend *SecurityFrontend) validateIdToken(rawIDToken string, non
 
 	var claims struct {
 		Email        string `json:"email"`
+		Name         string `json:"name"`
 		GSuiteDomain string `json:"hd"`
 	}
 
@@ -264,10 +347,13 @@ func (securityFrontend *SecurityFrontend) validateIdToken(rawIDToken string, non
 		return WebUser{}, fmt.Errorf("GSuite domain '%s' is not allowed", claims.GSuiteDomain)
 	}
 
-	return WebUser{Username: claims.Email, Type: TypeOidcUser}, nil
+	return WebUser{Username: claims.Email, Name: claims.Name, Type: TypeOidcUser}, nil
 }
 
-func (securityFrontend *SecurityFrontend) handleOidcAuthentication(w http.ResponseWriter, r *http.Request) (*WebUser, bool) {
+// handleOidcAuthentication returns the user authenticated in the current
+// session. If there is none, interactive clients are redirected to the
+// OpenID Connect provider while the others get a 401 error.
+func (securityFrontend *SecurityFrontend) handleOidcAuthentication(w http.ResponseWriter, r *http.Request, interactive bool) (*WebUser, bool) {
 	session, err := securityFrontend.store.Get(r, "oidc")
 	if err != nil {
 		log.Printf("session.Store.Get: %s", err)
@@ -276,28 +362,75 @@ func (securityFrontend *SecurityFrontend) handleOidcAuthentication(w http.Respon
 	}
 
 	u := session.Values["user"]
-	if u == nil {
+	if u == nil && !interactive {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return &WebUser{}, false
+	} else if u == nil {
 		securityFrontend.handleOidcRedirect(w, r, session, "")
 		return &WebUser{}, false
 	}
 
-	user, ok := u.(*WebUser)
+	sessionUser, ok := u.(*WebUser)
 	if !ok {
 		log.Println("Cannot cast session item 'user' as WebUser")
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return &WebUser{}, false
 	}
 
-	return user, true
+	// Scopes are not part of the session so that configuration changes
+	// are taken into account immediately
+	user := *sessionUser
+	user.Scopes = []string{ScopeRead, ScopeUpload}
+	if securityFrontend.Admins[user.Username] {
+		user.Scopes = append(user.Scopes, ScopeAdmin)
+	}
+
+	return &user, true
+}
+
+// handleCSRFProtection returns the anti-CSRF token of the current session,
+// generating it if needed, and checks that forms submitted with POST carry it.
+func (securityFrontend *SecurityFrontend) handleCSRFProtection(w http.ResponseWriter, r *http.Request) (string, bool) {
+	session, err := securityFrontend.store.Get(r, "oidc")
+	if err != nil {
+		log.Printf("session.Store.Get: %s", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return "", false
+	}
+
+	token, _ := session.Values["csrf"].(string)
+	if token == "" {
+		secret, err := newRandomSecret(32)
+		if err != nil {
+			log.Printf("rand.Read: %s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return "", false
+		}
+
+		token = secret.String()
+		session.Values["csrf"] = token
+		err = session.Save(r, w)
+		if err != nil {
+			log.Printf("Session.Save: %s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return "", false
+		}
+	}
+
+	if r.Method == "POST" && subtle.ConstantTimeCompare([]byte(r.PostFormValue("csrf")), []byte(token)) != 1 {
+		log.Printf("%s %s: invalid CSRF token", r.Method, r.URL.Path)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return "", false
+	}
+
+	return token, true
 }
 
 func (securityFrontend *SecurityFrontend) handleTelegramTokenAuthentication(w http.ResponseWriter, r *http.Request) (*WebUser, bool) {
 	var username, token string
 	username, r.URL.Path = ShiftPath(r.URL.Path)
 	token, r.URL.Path = ShiftPath(r.URL.Path)
-	var tail string
-	_, tail = ShiftPath(r.URL.Path)
-	album, _ := ShiftPath(tail)
+	album := albumFromPath(r.URL.Path)
 
 	data := TokenData{
 		Username:    username,
@@ -321,5 +454,110 @@ func (securityFrontend *SecurityFrontend) handleTelegramTokenAuthentication(w ht
 		}
 	}
 
-	return &WebUser{Username: username, Type: TypeTelegramUser}, true
+	return &WebUser{Username: username, Type: TypeTelegramUser, Scopes: []string{ScopeRead}}, true
+}
+
+// handlePreviewAuthentication validates the signed URLs of the images shown
+// in link previews (/preview/<expires>/<signature>/album/<album>/raw/<file>).
+// They give access to this single file, for a short time.
+func (securityFrontend *SecurityFrontend) handlePreviewAuthentication(w http.ResponseWriter, r *http.Request) (*WebUser, bool) {
+	var expires, signature string
+	expires, r.URL.Path = ShiftPath(r.URL.Path)
+	signature, r.URL.Path = ShiftPath(r.URL.Path)
+
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	timestamp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !securityFrontend.TokenGenerator.ValidatePath(r.URL.Path, time.Unix(timestamp, 0), signature) {
+		http.Error(w, "Invalid Token", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return &WebUser{Username: "preview", Type: TypePreview, Scopes: []string{ScopeRead}}, true
+}
+
+// handleWebDAVAuthentication authenticates the WebDAV clients, either with an
+// API token (as bearer or as password) or with the password of a WebDAV user.
+// WebDAV is read-only: the read scope is required for all methods.
+func (securityFrontend *SecurityFrontend) handleWebDAVAuthentication(w http.ResponseWriter, r *http.Request) (*WebUser, bool) {
+	var user *WebUser
+	if bearer, ok := getBearerToken(r); ok {
+		user, ok = securityFrontend.handleBearerAuthentication(w, r, bearer)
+		if !ok {
+			return nil, false
+		}
+	} else if username, password, ok := r.BasicAuth(); ok {
+		// Viper lowercases the keys of the configuration
+		if hash, found := securityFrontend.WebDAVUsers[strings.ToLower(username)]; found {
+			if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+				user = &WebUser{Username: username, Type: TypeWebDAVUser, Scopes: []string{ScopeRead}}
+			}
+		} else if securityFrontend.APITokens != nil {
+			if token, valid := securityFrontend.APITokens.Validate(password); valid {
+				user = &WebUser{Username: token.Name, Type: TypeAPIToken, Scopes: token.Scopes}
+			}
+		}
+	}
+
+	if user == nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="WebDAV", charset="UTF-8"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if !user.HasScope(ScopeRead) {
+		log.Printf("[%s] %s %s: no read scope", user, r.Method, r.URL.Path)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// getBearerToken extracts the token from the "Authorization: Bearer" header
+func getBearerToken(r *http.Request) (string, bool) {
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) < 7 || !strings.EqualFold(authorization[0:7], "Bearer ") {
+		return "", false
+	}
+
+	return strings.TrimSpace(authorization[7:]), true
+}
+
+func (securityFrontend *SecurityFrontend) handleBearerAuthentication(w http.ResponseWriter, r *http.Request, bearer string) (*WebUser, bool) {
+	if securityFrontend.APITokens != nil {
+		token, ok := securityFrontend.APITokens.Validate(bearer)
+		if ok {
+			return &WebUser{Username: token.Name, Type: TypeAPIToken, Scopes: token.Scopes}, true
+		}
+	}
+
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	http.Error(w, "Invalid Token", http.StatusUnauthorized)
+	return nil, false
+}
+
+// albumFromPath returns the album targeted by a request, for per-album
+// entitlements. It returns an empty string if the request does not target
+// a specific album.
+func albumFromPath(p string) string {
+	resource, tail := ShiftPath(p)
+	switch resource {
+	case "album":
+		album, _ := ShiftPath(tail)
+		return album
+	case "api":
+		// /api/v1/albums/<album>/...
+		_, tail = ShiftPath(tail)
+		collection, tail := ShiftPath(tail)
+		if collection == "albums" {
+			album, _ := ShiftPath(tail)
+			return album
+		}
+	}
+
+	return ""
 }
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestAlbumFromPath(t *testing.T) {
+	assert.Equal(t, albumFromPath("/album/"), "", "album index")
+	assert.Equal(t, albumFromPath("/album/2020-05-01-test/"), "2020-05-01-test", "album page")
+	assert.Equal(t, albumFromPath("/album/latest/media/1234/"), "latest", "media page")
+	assert.Equal(t, albumFromPath("/api/v1/albums"), "", "API album list")
+	assert.Equal(t, albumFromPath("/api/v1/albums/latest/media/1234"), "latest", "API media")
+	assert.Equal(t, albumFromPath("/api/v1/other/latest"), "", "unknown API collection")
+	assert.Equal(t, albumFromPath("/other/latest"), "", "unknown resource")
+}
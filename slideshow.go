@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// SlideshowSettings holds the defaults of the slideshow, that can be changed
+// with the query parameters of the slideshow page
+type SlideshowSettings struct {
+	Interval int // in seconds
+	Shuffle  bool
+	Captions bool
+}
+
+type slide struct {
+	Type    string `json:"type"`
+	Photo   string `json:"photo,omitempty"`
+	Video   string `json:"video,omitempty"`
+	Caption string `json:"caption,omitempty"`
+}
+
+// getSlideshowSettings overrides the defaults with the query parameters
+// (interval, shuffle and captions)
+func (web *WebInterface) getSlideshowSettings(r *http.Request) SlideshowSettings {
+	settings := web.Slideshow
+	query := r.URL.Query()
+	if interval, err := strconv.Atoi(query.Get("interval")); err == nil && interval > 0 {
+		settings.Interval = interval
+	}
+	if shuffle, err := strconv.ParseBool(query.Get("shuffle")); err == nil {
+		settings.Shuffle = shuffle
+	}
+	if captions, err := strconv.ParseBool(query.Get("captions")); err == nil {
+		settings.Captions = captions
+	}
+	return settings
+}
+
+func (web *WebInterface) handleSlideshow(w http.ResponseWriter, r *http.Request, albumName string) {
+	if albumName == "latest" {
+		albumName = ""
+	}
+
+	album, err := web.MediaStore.GetAlbum(albumName, false)
+	if errors.Is(err, ErrAlbumNotFound) {
+		web.handleFileNotFound(w, r)
+		return
+	} else if err != nil {
+		log.Printf("MediaStore.GetAlbum: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	slides := make([]slide, 0, len(album.Media))
+	for _, media := range album.Media {
+		s := slide{
+			Type:    media.Type,
+			Photo:   findFileWithSuffix(media.Files, ".jpeg"),
+			Caption: media.Caption,
+		}
+		if media.Type == "video" {
+			s.Video = findFileWithSuffix(media.Files, ".mp4")
+		}
+
+		// Skip the media whose files are missing
+		if s.Photo == "" && s.Video == "" {
+			continue
+		}
+		slides = append(slides, s)
+	}
+
+	err = web.SlideshowTemplate.Execute(w, struct {
+		I18n     I18n
+		Album    *Album
+		Slides   []slide
+		Settings SlideshowSettings
+	}{
+		web.negotiateLanguage(w, r),
+		album,
+		slides,
+		web.getSlideshowSettings(r),
+	})
+	if err != nil {
+		log.Printf("Template.Execute: %s", err)
+		web.handleError(w, r)
+		return
+	}
+}
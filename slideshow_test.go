@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestGetSlideshowSettings(t *testing.T) {
+	web := &WebInterface{Slideshow: SlideshowSettings{Interval: 5, Captions: true}}
+
+	settings := web.getSlideshowSettings(httptest.NewRequest("GET", "/album/latest/slideshow/", nil))
+	assert.Equal(t, settings, SlideshowSettings{Interval: 5, Captions: true}, "defaults")
+
+	settings = web.getSlideshowSettings(httptest.NewRequest("GET", "/album/latest/slideshow/?interval=10&shuffle=1&captions=false", nil))
+	assert.Equal(t, settings, SlideshowSettings{Interval: 10, Shuffle: true, Captions: false}, "query parameters")
+
+	settings = web.getSlideshowSettings(httptest.NewRequest("GET", "/album/latest/slideshow/?interval=-1&shuffle=maybe", nil))
+	assert.Equal(t, settings, SlideshowSettings{Interval: 5, Captions: true}, "invalid values are ignored")
+}
+
+func TestSlideshowRedirect(t *testing.T) {
+	web := &WebInterface{I18n: NewWebCatalog("en", map[string]I18n{"en": {}})}
+	user := &WebUser{Username: "john", Type: TypeTelegramUser, Scopes: []string{ScopeRead}}
+
+	r := httptest.NewRequest("GET", "/album/latest/slideshow?interval=10", nil)
+	w := httptest.NewRecorder()
+	web.ServeHTTP(w, withWebUser(r, user, "/s/john/token"))
+	assert.Equal(t, w.Code, http.StatusMovedPermanently, "the slideshow needs a trailing slash")
+	assert.Equal(t, w.Header().Get("Location"), "/s/john/token/album/latest/slideshow/?interval=10", "the settings are kept")
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+var hashtagRegexp = regexp.MustCompile(`#([\p{L}\p{N}_]+)`)
+
+// parseHashtags returns the hashtags of a caption, without the leading "#",
+// lowercase, without diacritics and without duplicates
+func parseHashtags(caption string) []string {
+	var tags []string
+	for _, match := range hashtagRegexp.FindAllStringSubmatch(caption, -1) {
+		tags = addTags(tags, match[1])
+	}
+	return tags
+}
+
+// addTags adds tags to a list, if not already there
+func addTags(tags []string, added ...string) []string {
+	for _, tag := range added {
+		tag = foldText(strings.TrimPrefix(tag, "#"))
+		if tag != "" && !hasTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// removeTags removes tags from a list
+func removeTags(tags []string, removed ...string) []string {
+	var result []string
+	for _, tag := range tags {
+		if !hasTag(removed, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// LocateMedia returns the album (its id, empty for the current album)
+// holding a media
+func (store *MediaStore) LocateMedia(mediaId string) (string, error) {
+	files, err := ioutil.ReadDir(store.StoreLocation)
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+
+		album, err := store.getCachedAlbum(file.Name())
+		if err != nil {
+			log.Printf("LocateMedia: Cannot extract album info for '%s'", file.Name())
+			continue
+		}
+
+		if _, err := findMedia(album.Media, mediaId); err == nil {
+			return album.ID, nil
+		}
+	}
+
+	return "", ErrMediaNotFound
+}
+
+// UpdateTags adds and removes tags of a media and returns its new tags
+func (store *MediaStore) UpdateTags(mediaId string, added []string, removed []string) ([]string, error) {
+	albumId, err := store.LocateMedia(mediaId)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	err = store.updateAlbumContent(albumId, func(media []Media) ([]Media, error) {
+		i, err := findMedia(media, mediaId)
+		if err != nil {
+			return nil, err
+		}
+
+		media[i].Tags = removeTags(addTags(media[i].Tags, added...), removed...)
+		tags = media[i].Tags
+		return media, nil
+	})
+
+	return tags, err
+}
+
+// ListTaggedMedia returns the albums having media with the given tag, with
+// only those media
+func (store *MediaStore) ListTaggedMedia(tag string) (AlbumList, error) {
+	tag = foldText(tag)
+	return store.filterAlbums(func(media Media) bool {
+		return hasTag(media.Tags, tag)
+	})
+}
+
+// A TagCount is a tag and the number of media having this tag
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// ListTags returns all the tags, the most used first
+func (store *MediaStore) ListTags() ([]TagCount, error) {
+	albums, err := store.filterAlbums(func(media Media) bool {
+		return len(media.Tags) > 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, album := range albums {
+		for _, media := range album.Media {
+			for _, tag := range media.Tags {
+				counts[tag]++
+			}
+		}
+	}
+
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+
+	return tags, nil
+}
+
+// handleDisplayTag lists the media having a tag, or all tags if tag is empty
+func (web *WebInterface) handleDisplayTag(w http.ResponseWriter, r *http.Request, tag string) {
+	var tags []TagCount
+	var albums AlbumList
+	var err error
+	if tag == "" {
+		tags, err = web.MediaStore.ListTags()
+	} else {
+		albums, err = web.MediaStore.ListTaggedMedia(tag)
+		sort.Sort(sort.Reverse(albums))
+	}
+	if err != nil {
+		log.Printf("MediaStore.ListTags: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	err = web.TagTemplate.Execute(w, struct {
+		I18n   I18n
+		Tag    string
+		Tags   []TagCount
+		Albums AlbumList
+	}{
+		web.negotiateLanguage(w, r),
+		tag,
+		tags,
+		albums,
+	})
+	if err != nil {
+		log.Printf("Template.Execute: %s", err)
+		web.handleError(w, r)
+		return
+	}
+}
+
+// parseTagCommand parses a reply made of "#tag" (to add) and "-#tag" (to
+// remove) words. It returns false if the text has anything else, including
+// tags that would not be recognized in a caption (such as "#beach,").
+func parseTagCommand(text string) ([]string, []string, bool) {
+	var added, removed []string
+	for _, word := range strings.Fields(text) {
+		tag := strings.TrimPrefix(word, "-")
+		if tag == "" || hashtagRegexp.FindString(tag) != tag {
+			return nil, nil, false
+		}
+
+		if tag != word {
+			removed = addTags(removed, tag[1:])
+		} else {
+			added = addTags(added, tag[1:])
+		}
+	}
+
+	return added, removed, len(added)+len(removed) > 0
+}
+
+// handleMediaReply acts on a media when a user replies to its message:
+// tags change the tags of the media, anything else is a comment.
+func (bot *TelegramBot) handleMediaReply(message *tgbotapi.Message, mediaId string) {
+	messages := bot.messagesFor(message)
+	username := message.From.UserName
+
+	added, removed, ok := parseTagCommand(message.Text)
+	if !ok {
+		bot.handleComment(message, mediaId)
+		return
+	}
+
+	tags, err := bot.MediaStore.UpdateTags(mediaId, added, removed)
+	if err != nil {
+		log.Printf("[%s] cannot update the tags of media %s: %s", username, mediaId, err)
+		bot.replyToCommandWithMessage(message, messages.ServerError)
+		return
+	}
+
+	log.Printf("[%s] tags of media %s: +%v -%v", username, mediaId, added, removed)
+	if len(tags) == 0 {
+		bot.replyWithMessage(message, messages.TagsNone)
+		return
+	}
+	bot.replyWithMessage(message, fmt.Sprintf(messages.TagsUpdated, "#"+strings.Join(tags, " #")))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestParseHashtags(t *testing.T) {
+	assert.Equal(t, parseHashtags("At the #beach with the #Kids! #beach #Été_2020"), []string{"beach", "kids", "ete_2020"}, "hashtags are folded and deduplicated")
+	assert.Equal(t, len(parseHashtags("No tags here # at all")), 0, "no hashtags")
+}
+
+func TestParseTagCommand(t *testing.T) {
+	added, removed, ok := parseTagCommand("#beach -#Kids")
+	assert.Equal(t, ok, true, "valid tag command")
+	assert.Equal(t, added, []string{"beach"}, "added tags")
+	assert.Equal(t, removed, []string{"kids"}, "removed tags")
+
+	_, _, ok = parseTagCommand("nice photo #beach")
+	assert.Equal(t, ok, false, "regular replies are not tag commands")
+
+	for _, text := range []string{"#beach,", "#a/b", "-", "-#", "--#beach"} {
+		_, _, ok = parseTagCommand(text)
+		assert.Equal(t, ok, false, "invalid tag "+text)
+	}
+}
+
+func TestUpdateTags(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+
+	createTestAlbum(t, store, "Holidays")
+
+	id := addTestPhoto(t, store, time.Now(), "Sand castle #beach #kids", "")
+
+	media, err := store.GetMedia("", id)
+	if err != nil {
+		t.Fatalf("GetMedia(): error %s", err)
+	}
+	assert.Equal(t, media.Tags, []string{"beach", "kids"}, "hashtags are parsed at commit time")
+
+	// Tags can still be changed once the album is closed
+	createTestAlbum(t, store, "Birthday")
+
+	tags, err := store.UpdateTags(id, []string{"sea"}, []string{"kids"})
+	if err != nil {
+		t.Fatalf("UpdateTags(): error %s", err)
+	}
+	assert.Equal(t, tags, []string{"beach", "sea"}, "tags have been added and removed")
+
+	_, err = store.UpdateTags("unknown", []string{"sea"}, nil)
+	assert.Equal(t, err, ErrMediaNotFound, "unknown media")
+
+	albums, err := store.ListTaggedMedia("Sea")
+	if err != nil {
+		t.Fatalf("ListTaggedMedia(): error %s", err)
+	}
+	assert.Equal(t, len(albums), 1, "one album has the tag")
+	assert.Equal(t, albums[0].Media[0].ID, id, "the media has the tag")
+
+	list, err := store.ListTags()
+	if err != nil {
+		t.Fatalf("ListTags(): error %s", err)
+	}
+	assert.Equal(t, list, []TagCount{{Tag: "beach", Count: 1}, {Tag: "sea", Count: 1}}, "all tags are listed")
+}
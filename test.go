@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 )
 
 type TestCaseTempFile struct {
@@ -22,3 +23,38 @@ func createTempDir(t *testing.T) TestCaseTempFile {
 func (tmp *TestCaseTempFile) cleanup(t *testing.T) {
 	//os.RemoveAll(tmp.RootDir)
 }
+
+// createTestMediaStore initializes a MediaStore in the temporary directory
+func createTestMediaStore(t *testing.T, tmp TestCaseTempFile) *MediaStore {
+	store, err := InitMediaStore(tmp.RootDir)
+	if err != nil {
+		t.Fatalf("InitMediaStore(): error %s", err)
+	}
+	return store
+}
+
+// createTestAlbum closes the current album and starts a new one
+func createTestAlbum(t *testing.T, store *MediaStore, title string) {
+	err := store.NewAlbum(title)
+	if err != nil {
+		t.Fatalf("NewAlbum(): error %s", err)
+	}
+}
+
+// addTestPhoto adds a photo (not a real JPEG file) to the current album and
+// returns its id
+func addTestPhoto(t *testing.T, store *MediaStore, date time.Time, caption string, uploader string) string {
+	id := store.GetUniqueID()
+	fd, err := store.AddFile(id + ".jpeg")
+	if err != nil {
+		t.Fatalf("AddFile(): error %s", err)
+	}
+	fd.WriteString("JPEG File")
+	fd.Close()
+
+	err = store.CommitPhoto(id, date, caption, uploader)
+	if err != nil {
+		t.Fatalf("CommitPhoto(): error %s", err)
+	}
+	return id
+}
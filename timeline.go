@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// A TimelineYear groups the media taken during a year, by month
+type TimelineYear struct {
+	Year   int
+	Count  int
+	Months []TimelineMonth
+}
+
+type TimelineMonth struct {
+	Month time.Month
+	Media []TimelineMedia
+}
+
+// A TimelineMedia is a media along with the id of its album ("latest" for the
+// current album)
+type TimelineMedia struct {
+	Media
+	AlbumID string
+}
+
+// GetTimeline returns the media of all albums grouped by year and month,
+// the most recent first
+func (store *MediaStore) GetTimeline() ([]TimelineYear, error) {
+	files, err := ioutil.ReadDir(store.StoreLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []TimelineMedia
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+
+		album, err := store.getCachedAlbum(file.Name())
+		if err != nil {
+			log.Printf("GetTimeline: Cannot extract album info for '%s'", file.Name())
+			continue
+		}
+
+		id := album.ID
+		if id == "" {
+			id = "latest"
+		}
+		for _, media := range album.Media {
+			all = append(all, TimelineMedia{Media: media, AlbumID: id})
+		}
+	}
+
+	return groupByMonth(all), nil
+}
+
+// groupByMonth builds the timeline of a list of media, in the local time zone
+func groupByMonth(all []TimelineMedia) []TimelineYear {
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].TakenDate().After(all[j].TakenDate())
+	})
+
+	var timeline []TimelineYear
+	for _, media := range all {
+		taken := media.TakenDate().Local()
+		if len(timeline) == 0 || timeline[len(timeline)-1].Year != taken.Year() {
+			timeline = append(timeline, TimelineYear{Year: taken.Year()})
+		}
+
+		year := &timeline[len(timeline)-1]
+		if len(year.Months) == 0 || year.Months[len(year.Months)-1].Month != taken.Month() {
+			year.Months = append(year.Months, TimelineMonth{Month: taken.Month()})
+		}
+
+		month := &year.Months[len(year.Months)-1]
+		month.Media = append(month.Media, media)
+		year.Count++
+	}
+
+	return timeline
+}
+
+func (web *WebInterface) handleDisplayTimeline(w http.ResponseWriter, r *http.Request) {
+	timeline, err := web.MediaStore.GetTimeline()
+	if err != nil {
+		log.Printf("MediaStore.GetTimeline: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	err = web.TimelineTemplate.Execute(w, struct {
+		I18n     I18n
+		Timeline []TimelineYear
+	}{
+		web.negotiateLanguage(w, r),
+		timeline,
+	})
+	if err != nil {
+		log.Printf("Template.Execute: %s", err)
+		web.handleError(w, r)
+		return
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestGroupByMonth(t *testing.T) {
+	media := []TimelineMedia{
+		{Media: Media{ID: "1", Date: time.Date(2019, 12, 24, 20, 0, 0, 0, time.Local)}},
+		{Media: Media{ID: "2", Date: time.Date(2020, 5, 1, 10, 0, 0, 0, time.Local)}},
+		{Media: Media{ID: "3", Date: time.Date(2020, 7, 1, 10, 0, 0, 0, time.Local), TakenAt: time.Date(2020, 5, 2, 10, 0, 0, 0, time.Local)}},
+		{Media: Media{ID: "4", Date: time.Date(2020, 8, 1, 10, 0, 0, 0, time.Local)}},
+	}
+
+	timeline := groupByMonth(media)
+	assert.Equal(t, len(timeline), 2, "two years")
+	assert.Equal(t, timeline[0].Year, 2020, "most recent year first")
+	assert.Equal(t, timeline[0].Count, 3, "media of 2020")
+	assert.Equal(t, len(timeline[0].Months), 2, "two months in 2020")
+	assert.Equal(t, timeline[0].Months[0].Month, time.August, "most recent month first")
+	assert.Equal(t, len(timeline[0].Months[1].Media), 2, "the date the media has been taken is used")
+	assert.Equal(t, timeline[0].Months[1].Media[0].ID, "3", "most recent media first")
+	assert.Equal(t, timeline[1].Months[0].Media[0].ID, "1", "media of 2019")
+}
+
+func TestGetTimeline(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+
+	createTestAlbum(t, store, "Holidays")
+	addTestPhoto(t, store, time.Date(2020, 5, 1, 10, 0, 0, 0, time.Local), "", "")
+	timeline, err := store.GetTimeline()
+	if err != nil {
+		t.Fatalf("GetTimeline(): error %s", err)
+	}
+	assert.Equal(t, len(timeline), 1, "one year")
+	assert.Equal(t, timeline[0].Months[0].Media[0].AlbumID, "latest", "media of the current album")
+
+	// The cached album is refreshed when a media is added
+	addTestPhoto(t, store, time.Date(2021, 1, 1, 10, 0, 0, 0, time.Local), "", "")
+	timeline, err = store.GetTimeline()
+	if err != nil {
+		t.Fatalf("GetTimeline(): error %s", err)
+	}
+	assert.Equal(t, len(timeline), 2, "the new media is part of the timeline")
+
+	// And when the album is closed
+	createTestAlbum(t, store, "Birthday")
+	timeline, err = store.GetTimeline()
+	if err != nil {
+		t.Fatalf("GetTimeline(): error %s", err)
+	}
+	assert.Equal(t, timeline[0].Count, 1, "the media are still part of the timeline")
+	assert.Equal(t, timeline[0].Months[0].Media[0].AlbumID != "latest", true, "the media moved to the closed album")
+}
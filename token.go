@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -89,3 +90,21 @@ func (g *TokenGenerator) ValidateToken(data TokenData, token string, validity in
 
 	return false, nil
 }
+
+// SignPath returns a signature granting access to a single path until the
+// given date. The data is prefixed so that a signature can never be mistaken
+// for a share token.
+func (g *TokenGenerator) SignPath(p string, expires time.Time) string {
+	hasher := hmac.New(g.Algorithm.New, g.AuthenticationKey)
+	hasher.Write([]byte("path\x00" + strconv.FormatInt(expires.Unix(), 10) + "\x00" + p))
+	return base64.RawURLEncoding.EncodeToString(hasher.Sum(nil))
+}
+
+// ValidatePath checks the signature of a path and that it has not expired
+func (g *TokenGenerator) ValidatePath(p string, expires time.Time, signature string) bool {
+	if time.Now().After(expires) {
+		return false
+	}
+
+	return hmac.Equal([]byte(g.SignPath(p, expires)), []byte(signature))
+}
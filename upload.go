@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// UploadSettings controls the uploads through the web interface
+type UploadSettings struct {
+	Enabled        bool
+	MaxFileSize    int64 // in bytes
+	MaxRequestSize int64 // in bytes, all files of an upload
+}
+
+var errUnsupportedMedia = errors.New("Unsupported media type")
+var errFileTooLarge = errors.New("File too large")
+
+type uploadResult struct {
+	Uploaded []string `json:"uploaded"`
+}
+
+// handleUpload receives photos and videos sent as a multipart form and
+// stores them in the current album. Files are streamed to the MediaStore,
+// without being buffered in memory.
+func (web *WebInterface) handleUpload(w http.ResponseWriter, r *http.Request, albumName string) {
+	user := GetWebUser(r)
+	if !web.Upload.Enabled || user == nil || !user.HasScope(ScopeUpload) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Media can only be added to the current album
+	if albumName != "latest" {
+		http.Error(w, "Uploads are only allowed in the latest album", http.StatusBadRequest)
+		return
+	}
+
+	if r.ContentLength > web.Upload.MaxRequestSize {
+		http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, web.Upload.MaxRequestSize)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var uploaded []string
+	var caption string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if isRequestTooLarge(err) {
+			http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			log.Printf("[%s] multipart.NextPart: %s", user, err)
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		// The caption applies to the files following it
+		if part.FormName() == "caption" && part.FileName() == "" {
+			value, err := ioutil.ReadAll(io.LimitReader(part, 1024))
+			if isRequestTooLarge(err) {
+				http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
+				return
+			} else if err != nil {
+				http.Error(w, "Bad request", http.StatusBadRequest)
+				return
+			}
+			caption = strings.TrimSpace(string(value))
+			continue
+		}
+
+		if part.FormName() != "file" || part.FileName() == "" {
+			continue
+		}
+
+		id, err := web.storeUpload(part, caption, user.String())
+		if err == errUnsupportedMedia {
+			http.Error(w, fmt.Sprintf("%s: %s", err, part.FileName()), http.StatusUnsupportedMediaType)
+			return
+		} else if err == errFileTooLarge {
+			http.Error(w, fmt.Sprintf("%s: %s", err, part.FileName()), http.StatusRequestEntityTooLarge)
+			return
+		} else if isRequestTooLarge(err) {
+			http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			log.Printf("[%s] Cannot store uploaded file '%s': %s", user, part.FileName(), err)
+			web.handleError(w, r)
+			return
+		}
+
+		log.Printf("[%s] Uploaded '%s' as media %s", user, part.FileName(), id)
+		uploaded = append(uploaded, id)
+	}
+
+	if len(uploaded) == 0 {
+		http.Error(w, "No file uploaded", http.StatusBadRequest)
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		web.apiResponse(w, uploadResult{Uploaded: uploaded}, http.StatusCreated)
+		return
+	}
+
+	// Non-JavaScript clients go back to the album
+	http.Redirect(w, r, GetBasePath(r)+"/album/latest/", http.StatusSeeOther)
+}
+
+// isRequestTooLarge returns true if the error comes from the MaxBytesReader
+// of the request body, when the Content-Length was missing or wrong. This
+// error has no type of its own before Go 1.19.
+func isRequestTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "request body too large")
+}
+
+// storeUpload writes a file to the MediaStore and commits it as a photo or a
+// video, depending on its content. Partial files are discarded.
+func (web *WebInterface) storeUpload(part *multipart.Part, caption string, uploader string) (string, error) {
+	// Only the first 512 bytes are used to sniff the content type.
+	buffer := make([]byte, 512)
+	n, err := io.ReadFull(part, buffer)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	var extension, mediaType string
+	contentType := http.DetectContentType(buffer[0:n])
+	if contentType == "image/jpeg" {
+		extension, mediaType = ".jpeg", "photo"
+	} else if contentType == "video/mp4" {
+		extension, mediaType = ".mp4", "video"
+	} else {
+		return "", errUnsupportedMedia
+	}
+
+	id := web.MediaStore.GetUniqueID()
+	filename := id + extension
+	out, err := web.MediaStore.AddFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	// Write back the first 512 bytes, followed by the rest of the file.
+	// One more byte than allowed is read to detect files that are too large.
+	content := io.MultiReader(bytes.NewReader(buffer[0:n]), part)
+	written, err := io.Copy(out, io.LimitReader(content, web.Upload.MaxFileSize+1))
+	if err == nil && written > web.Upload.MaxFileSize {
+		err = errFileTooLarge
+	}
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if discardErr := web.MediaStore.DiscardFile(filename); discardErr != nil {
+			log.Printf("MediaStore.DiscardFile: %s", discardErr)
+		}
+		return "", err
+	}
+
+	if mediaType == "photo" {
+		err = web.MediaStore.CommitPhoto(id, time.Now(), caption, uploader)
+	} else {
+		err = web.MediaStore.CommitVideo(id, time.Now(), caption, uploader)
+	}
+
+	return id, err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func newUploadRequest(t *testing.T, user *WebUser, filename string, content []byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("caption", "Holidays")
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("CreateFormFile(): error %s", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	r := httptest.NewRequest("POST", "/album/latest/upload/", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	return withWebUser(r, user, "")
+}
+
+func TestUpload(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store, err := InitMediaStore(tmp.RootDir)
+	if err != nil {
+		t.Fatalf("InitMediaStore(): error %s", err)
+	}
+
+	web := &WebInterface{
+		MediaStore: store,
+		I18n:       NewWebCatalog("en", map[string]I18n{"en": {}}),
+		Upload:     UploadSettings{Enabled: true, MaxFileSize: 1024, MaxRequestSize: 2048},
+	}
+	jpeg := append([]byte{0xFF, 0xD8, 0xFF, 0xE0}, bytes.Repeat([]byte{0}, 100)...)
+	oidcUser := &WebUser{Username: "grandma@example.test", Type: TypeOidcUser, Scopes: []string{ScopeRead, ScopeUpload}}
+
+	// Telegram share links are read-only
+	w := httptest.NewRecorder()
+	web.ServeHTTP(w, newUploadRequest(t, &WebUser{Username: "john", Type: TypeTelegramUser, Scopes: []string{ScopeRead}}, "photo.jpeg", jpeg))
+	assert.Equal(t, w.Code, http.StatusForbidden, "read-only users cannot upload")
+
+	w = httptest.NewRecorder()
+	web.ServeHTTP(w, newUploadRequest(t, oidcUser, "notes.txt", []byte("Hello, World!")))
+	assert.Equal(t, w.Code, http.StatusUnsupportedMediaType, "only photos and videos are accepted")
+
+	w = httptest.NewRecorder()
+	web.ServeHTTP(w, newUploadRequest(t, oidcUser, "huge.jpeg", append(jpeg, bytes.Repeat([]byte{0}, 1024)...)))
+	assert.Equal(t, w.Code, http.StatusRequestEntityTooLarge, "files are limited in size")
+
+	w = httptest.NewRecorder()
+	web.ServeHTTP(w, newUploadRequest(t, oidcUser, "huge.jpeg", append(jpeg, bytes.Repeat([]byte{0}, 4096)...)))
+	assert.Equal(t, w.Code, http.StatusRequestEntityTooLarge, "requests are limited in size")
+
+	// Without Content-Length, the size is checked while reading the files
+	web.Upload.MaxFileSize = 4096
+	r := newUploadRequest(t, oidcUser, "huge.jpeg", append(jpeg, bytes.Repeat([]byte{0}, 3072)...))
+	r.ContentLength = -1
+	w = httptest.NewRecorder()
+	web.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusRequestEntityTooLarge, "streamed requests are limited in size")
+	web.Upload.MaxFileSize = 1024
+
+	files, _ := filepath.Glob(filepath.Join(tmp.RootDir, ".current", "*.jpeg"))
+	assert.Equal(t, len(files), 0, "partial files are discarded")
+
+	w = httptest.NewRecorder()
+	web.ServeHTTP(w, newUploadRequest(t, oidcUser, "photo.jpeg", jpeg))
+	assert.Equal(t, w.Code, http.StatusSeeOther, "the photo is uploaded")
+	assert.Equal(t, w.Header().Get("Location"), "/album/latest/", "the user is redirected to the album")
+
+	album, err := store.GetAlbum("", false)
+	if err != nil {
+		t.Fatalf("GetAlbum(): error %s", err)
+	}
+	assert.Equal(t, len(album.Media), 1, "the album has one media")
+	assert.Equal(t, album.Media[0].Type, "photo", "the media is a photo")
+	assert.Equal(t, album.Media[0].Caption, "Holidays", "the caption is recorded")
+	assert.Equal(t, album.Media[0].Uploader, "OIDC:grandma@example.test", "the uploader is recorded")
+
+	_, err = os.Stat(filepath.Join(tmp.RootDir, ".current", album.Media[0].ID+".jpeg"))
+	assert.Equal(t, err, nil, "the photo is stored in the current album")
+}
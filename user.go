@@ -1,6 +1,11 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
 
 type UserType int
 
@@ -8,6 +13,16 @@ const (
 	TypeAnonymous    UserType = 0
 	TypeTelegramUser UserType = 1
 	TypeOidcUser     UserType = 2
+	TypeAPIToken     UserType = 3
+	TypePreview      UserType = 4 // link preview crawlers, with a signed URL
+	TypeWebDAVUser   UserType = 5 // HTTP Basic users of the WebDAV export
+)
+
+// Scopes granted to the web users
+const (
+	ScopeRead   = "read"
+	ScopeUpload = "upload"
+	ScopeAdmin  = "admin" // implies all the other scopes
 )
 
 func (t UserType) String() string {
@@ -15,9 +30,12 @@ func (t UserType) String() string {
 		"Anonymous",
 		"Telegram",
 		"OIDC",
+		"API",
+		"Preview",
+		"WebDAV",
 	}
 
-	if t < TypeAnonymous || t > TypeOidcUser {
+	if t < TypeAnonymous || t > TypeWebDAVUser {
 		return "Unknown"
 	}
 
@@ -26,7 +44,54 @@ func (t UserType) String() string {
 
 type WebUser struct {
 	Username string
+	Name     string // display name, if known
 	Type     UserType
+	Scopes   []string
+}
+
+func (u WebUser) HasScope(scope string) bool {
+	for _, s := range u.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// CanInteract tells whether the user may mark favorites and comment media:
+// only people browsing the web interface can, not the API tokens (even
+// with the read scope) nor the link preview crawlers
+func (u WebUser) CanInteract() bool {
+	return (u.Type == TypeOidcUser || u.Type == TypeTelegramUser) && u.HasScope(ScopeRead)
+}
+
+// DisplayName returns the name under which the user is shown to the other
+// users. The email address of OIDC users is never shown.
+func (u WebUser) DisplayName() string {
+	if u.Name != "" {
+		return u.Name
+	}
+
+	return hideEmail(u.Username)
+}
+
+// hideEmail keeps only the local part of an email address
+func hideEmail(name string) string {
+	if i := strings.Index(name, "@"); i >= 0 {
+		return name[:i]
+	}
+
+	return name
+}
+
+// uploaderName returns the name to show for the uploader of a media, stored
+// as WebUser.String(): without the kind of account nor the email domain
+func uploaderName(uploader string) string {
+	if i := strings.Index(uploader, ":"); i >= 0 {
+		uploader = uploader[i+1:]
+	}
+
+	return hideEmail(uploader)
 }
 
 func (u WebUser) String() string {
@@ -36,3 +101,46 @@ func (u WebUser) String() string {
 
 	return fmt.Sprintf("%s:%s", u.Type, u.Username)
 }
+
+type contextKey int
+
+const (
+	webUserContextKey contextKey = iota
+	basePathContextKey
+	csrfTokenContextKey
+)
+
+// withWebUser attaches the authenticated user to the request, along with the
+// base path under which the web interface is reached (such as "/s/user/token"
+// for share links).
+func withWebUser(r *http.Request, user *WebUser, basePath string) *http.Request {
+	ctx := context.WithValue(r.Context(), webUserContextKey, user)
+	ctx = context.WithValue(ctx, basePathContextKey, basePath)
+	return r.WithContext(ctx)
+}
+
+// GetWebUser returns the user authenticated by the SecurityFrontend
+func GetWebUser(r *http.Request) *WebUser {
+	user, ok := r.Context().Value(webUserContextKey).(*WebUser)
+	if !ok {
+		return &WebUser{}
+	}
+	return user
+}
+
+// GetBasePath returns the path under which the web interface is reached
+func GetBasePath(r *http.Request) string {
+	basePath, _ := r.Context().Value(basePathContextKey).(string)
+	return basePath
+}
+
+// withCSRFToken attaches the anti-CSRF token of the session to the request
+func withCSRFToken(r *http.Request, token string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), csrfTokenContextKey, token))
+}
+
+// GetCSRFToken returns the anti-CSRF token to embed in the forms
+func GetCSRFToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfTokenContextKey).(string)
+	return token
+}
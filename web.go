@@ -1,10 +1,12 @@
 package main
 
 import (
+	"errors"
 	"html/template"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
@@ -15,14 +17,91 @@ type WebInterface struct {
 	AlbumTemplate *template.Template
 	MediaTemplate *template.Template
 	IndexTemplate *template.Template
-	I18n          I18n
+	I18n          *WebCatalog
+	Upload        UploadSettings
+	Audit         *AuditLog
+	PageSize      int // number of media per page of an album
+	FeedSize      int // number of media in the Atom feeds
+	PublicURL     string
+
+	// Signs the image URLs of the link previews
+	TokenGenerator  *TokenGenerator
+	PreviewValidity time.Duration
+
+	// Origins allowed to frame the embedded album widget
+	Headers        SecurityHeaders
+	FrameAncestors []string
+
+	Slideshow SlideshowSettings
+	Map       MapSettings
+
+	SlideshowTemplate  *template.Template
+	MapTemplate        *template.Template
+	TimelineTemplate   *template.Template
+	SearchTemplate     *template.Template
+	TagTemplate        *template.Template
+	EmbedTemplate      *template.Template
+	Favorites          *FavoritesDB
+	MessageDB          *MessageDB // forgets the deleted media
+	AdminTemplate      *template.Template
+	AdminAlbumTemplate *template.Template
+
+	AdminTokensTemplate *template.Template
+	APITokens           *APITokenStore
 }
 
 type I18n struct {
-	SiteName  string
-	Bio       string
-	LastMedia string
-	AllAlbums string
+	Lang              string
+	LanguageName      string
+	SiteName          string
+	Bio               string
+	LastMedia         string
+	AllAlbums         string
+	NotFound          string
+	ServerError       string
+	Upload            string
+	UploadButton      string
+	UploadError       string
+	UploadCaption     string
+	Download          string
+	Select            string
+	DownloadSelection string
+	Cancel            string
+	Previous          string
+	Next              string
+	Slideshow         string
+	Map               string
+	ZoomIn            string
+	ZoomOut           string
+	NoLocation        string
+	Timeline          string
+	Search            string
+	NoResult          string
+	Tags              string
+	Favorite          string
+	Highlights        string
+	AllMedia          string
+	Comments          string
+	AddComment        string
+	DateFormat        string
+	Months            []string
+
+	// Administration
+	Administration string
+	Rename         string
+	SetCover       string
+	Delete         string
+	ConfirmDelete  string
+	MoveUp         string
+	MoveDown       string
+	APITokens      string
+	TokenName      string
+	TokenScopes    string
+	TokenCreatedBy string
+	CreateToken    string
+	RevokeToken    string
+	ConfirmRevoke  string
+	TokenSecret    string
 }
 
 func NewWebInterface(statikFS http.FileSystem) (*WebInterface, error) {
@@ -44,6 +123,51 @@ func NewWebInterface(statikFS http.FileSystem) (*WebInterface, error) {
 		return nil, err
 	}
 
+	web.SlideshowTemplate, err = getTemplate(statikFS, "/slideshow.html.template", "slideshow")
+	if err != nil {
+		return nil, err
+	}
+
+	web.MapTemplate, err = getTemplate(statikFS, "/map.html.template", "map")
+	if err != nil {
+		return nil, err
+	}
+
+	web.TimelineTemplate, err = getTemplate(statikFS, "/timeline.html.template", "timeline")
+	if err != nil {
+		return nil, err
+	}
+
+	web.SearchTemplate, err = getTemplate(statikFS, "/search.html.template", "search")
+	if err != nil {
+		return nil, err
+	}
+
+	web.TagTemplate, err = getTemplate(statikFS, "/tag.html.template", "tag")
+	if err != nil {
+		return nil, err
+	}
+
+	web.EmbedTemplate, err = getTemplate(statikFS, "/embed.html.template", "embed")
+	if err != nil {
+		return nil, err
+	}
+
+	web.AdminTemplate, err = getTemplate(statikFS, "/admin.html.template", "admin")
+	if err != nil {
+		return nil, err
+	}
+
+	web.AdminAlbumTemplate, err = getTemplate(statikFS, "/admin-album.html.template", "admin-album")
+	if err != nil {
+		return nil, err
+	}
+
+	web.AdminTokensTemplate, err = getTemplate(statikFS, "/admin-tokens.html.template", "admin-tokens")
+	if err != nil {
+		return nil, err
+	}
+
 	return &web, nil
 }
 
@@ -71,23 +195,13 @@ func getTemplate(statikFS http.FileSystem, filename string, name string) (*templ
 
 	customFunctions := template.FuncMap{
 		"video": func(files []string) string {
-			for _, file := range files {
-				if strings.HasSuffix(file, ".mp4") {
-					return file
-				}
-			}
-			return ""
+			return findFileWithSuffix(files, ".mp4")
 		},
 		"photo": func(files []string) string {
-			for _, file := range files {
-				if strings.HasSuffix(file, ".jpeg") {
-					return file
-				}
-			}
-			return ""
+			return findFileWithSuffix(files, ".jpeg")
 		},
-		"short": func(t time.Time) string {
-			return t.Format("2006-01")
+		"short": func(i18n I18n, t time.Time) string {
+			return i18n.FormatDate(t)
 		},
 	}
 
@@ -95,11 +209,30 @@ func getTemplate(statikFS http.FileSystem, filename string, name string) (*templ
 }
 
 func (web *WebInterface) handleFileNotFound(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, "File not found", http.StatusNotFound)
+	http.Error(w, web.I18n.Negotiate(r).NotFound, http.StatusNotFound)
 }
 
 func (web *WebInterface) handleError(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, "Internal server error", http.StatusInternalServerError)
+	http.Error(w, web.I18n.Negotiate(r).ServerError, http.StatusInternalServerError)
+}
+
+// negotiateLanguage returns the strings in the user's language and
+// remembers the user's choice when the language is given explicitly
+func (web *WebInterface) negotiateLanguage(w http.ResponseWriter, r *http.Request) I18n {
+	i18n := web.I18n.Negotiate(r)
+	if r.URL.Query().Get(languageCookieName) == i18n.Lang {
+		http.SetCookie(w, &http.Cookie{
+			Name:     languageCookieName,
+			Value:    i18n.Lang,
+			Path:     "/",
+			MaxAge:   86400 * 365,
+			HttpOnly: true,
+		})
+	}
+
+	w.Header().Set("Content-Language", i18n.Lang)
+	w.Header().Add("Vary", "Accept-Language, Cookie")
+	return i18n
 }
 
 func (web *WebInterface) handleDisplayAlbum(w http.ResponseWriter, r *http.Request, albumName string) {
@@ -114,7 +247,61 @@ func (web *WebInterface) handleDisplayAlbum(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = web.AlbumTemplate.Execute(w, album)
+	// Media can only be uploaded to the current album
+	user := GetWebUser(r)
+	canUpload := web.Upload.Enabled && album.ID == "" && user != nil && user.HasScope(ScopeUpload)
+
+	highlights := web.filterAlbum(album, r)
+	page, err := web.getAlbumPage(album, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := album.ID
+	if id == "" {
+		id = "latest"
+	}
+
+	// The route is drawn from the locations shared during the album
+	track, err := web.MediaStore.GetTrack(album.ID)
+	if err != nil {
+		log.Printf("MediaStore.GetTrack: %s", err)
+		web.handleError(w, r)
+		return
+	}
+	var routeURL string
+	if len(track) > 0 {
+		routeURL = GetBasePath(r) + "/api/v1/albums/" + url.PathEscape(id) + "/locations"
+	}
+
+	// Absolute URL of the page, for the oEmbed discovery
+	pageURL := strings.TrimSuffix(web.PublicURL, "/") + GetBasePath(r) + "/album/" + url.PathEscape(id) + "/"
+
+	i18n := web.negotiateLanguage(w, r)
+	err = web.AlbumTemplate.Execute(w, struct {
+		I18n         I18n
+		Album        *Album
+		PageURL      string
+		Preview      LinkPreview
+		Page         Page
+		CanUpload    bool
+		RouteURL     string
+		Map          MapSettings
+		HasFavorites bool
+		Highlights   bool
+	}{
+		i18n,
+		album,
+		pageURL,
+		web.albumPreview(album, i18n),
+		page,
+		canUpload,
+		routeURL,
+		web.Map,
+		web.Favorites != nil,
+		highlights,
+	})
 	if err != nil {
 		log.Printf("Template.Execute: %s", err)
 		web.handleError(w, r)
@@ -152,10 +339,12 @@ func (web *WebInterface) handleDisplayIndex(w http.ResponseWriter, r *http.Reque
 
 	err = web.IndexTemplate.Execute(w, struct {
 		I18n      I18n
+		Languages []I18n
 		LastMedia []Media
 		Albums    []Album
 	}{
-		web.I18n,
+		web.negotiateLanguage(w, r),
+		web.I18n.All(),
 		lastMedia,
 		albums,
 	})
@@ -171,20 +360,67 @@ func (web *WebInterface) handleDisplayMedia(w http.ResponseWriter, r *http.Reque
 		albumName = ""
 	}
 
-	media, err := web.MediaStore.GetMedia(albumName, mediaId)
-	if err != nil {
-		log.Printf("MediaStore.GetMedia: %s", err)
+	album, err := web.MediaStore.GetAlbum(albumName, false)
+	if errors.Is(err, ErrAlbumNotFound) {
+		web.handleFileNotFound(w, r)
+		return
+	} else if err != nil {
+		log.Printf("MediaStore.GetAlbum: %s", err)
 		web.handleError(w, r)
 		return
-
 	}
 
-	if media == nil {
+	i, err := findMedia(album.Media, mediaId)
+	if err != nil {
 		web.handleFileNotFound(w, r)
 		return
 	}
 
-	err = web.MediaTemplate.Execute(w, media)
+	// Neighbours of the media within the album, for navigation
+	var previous, next *Media
+	if i > 0 {
+		previous = &album.Media[i-1]
+	}
+	if i < len(album.Media)-1 {
+		next = &album.Media[i+1]
+	}
+
+	// Favorites of the current user
+	var favorites *favoriteState
+	if user := GetWebUser(r); user.CanInteract() && web.Favorites != nil {
+		favorites = &favoriteState{
+			Favorite: web.Favorites.IsFavorite(user.String(), mediaId),
+			Count:    web.Favorites.Counts()[mediaId],
+		}
+	}
+
+	comments, err := web.MediaStore.GetComments(album.ID, mediaId)
+	if err != nil {
+		log.Printf("MediaStore.GetComments: %s", err)
+		web.handleError(w, r)
+		return
+	}
+
+	i18n := web.negotiateLanguage(w, r)
+	err = web.MediaTemplate.Execute(w, struct {
+		I18n       I18n
+		Media      *Media
+		Preview    LinkPreview
+		Previous   *Media
+		Next       *Media
+		Favorites  *favoriteState
+		Comments   []Comment
+		CanComment bool
+	}{
+		i18n,
+		&album.Media[i],
+		web.mediaPreview(album, &album.Media[i], i18n),
+		previous,
+		next,
+		favorites,
+		comments,
+		GetWebUser(r).CanInteract(),
+	})
 	if err != nil {
 		log.Printf("Template.Execute: %s", err)
 		web.handleError(w, r)
@@ -212,7 +448,14 @@ func (web *WebInterface) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var resource string
 	resource, r.URL.Path = ShiftPath(r.URL.Path)
 
-	if r.Method != "GET" {
+	if resource == "webdav" {
+		// WebDAV has its own methods
+		r.URL.Path = originalPath
+		web.serveWebDAV(w, r)
+		return
+	}
+
+	if r.Method != "GET" && r.Method != "HEAD" && r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
@@ -222,7 +465,65 @@ func (web *WebInterface) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		albumName, r.URL.Path = ShiftPath(r.URL.Path)
 		kind, r.URL.Path = ShiftPath(r.URL.Path)
 		media, r.URL.Path = ShiftPath(r.URL.Path)
-		if albumName != "" {
+
+		// Uploads and selective downloads are the only POST requests
+		if albumName != "" && kind == "upload" && media == "" {
+			if r.Method != "POST" {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			web.handleUpload(w, r, albumName)
+			return
+		} else if albumName != "" && kind == "download" && media == "" && r.Method == "POST" {
+			web.handleDownloadSelection(w, r, albumName)
+			return
+		} else if albumName != "" && kind == "favorite" && media != "" && r.Method == "POST" {
+			web.handleToggleFavorite(w, r, albumName, media)
+			return
+		} else if albumName != "" && kind == "comment" && media != "" && r.Method == "POST" {
+			web.handlePostComment(w, r, albumName, media)
+			return
+		} else if r.Method == "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if albumName == "map" && kind == "" {
+			// Map of all albums
+			if !strings.HasSuffix(originalPath, "/") {
+				http.Redirect(w, r, originalPath+"/", http.StatusMovedPermanently)
+				return
+			}
+			web.handleDisplayMap(w, r, "")
+			return
+		} else if albumName == "timeline" && kind == "" {
+			// Media of all albums, by year and month
+			if !strings.HasSuffix(originalPath, "/") {
+				http.Redirect(w, r, originalPath+"/", http.StatusMovedPermanently)
+				return
+			}
+			web.handleDisplayTimeline(w, r)
+			return
+		} else if albumName == "feed.atom" && kind == "" {
+			// Latest media of all albums
+			web.handleGlobalFeed(w, r)
+			return
+		} else if albumName == "search" && kind == "" {
+			if !strings.HasSuffix(originalPath, "/") {
+				http.Redirect(w, r, originalPath+"/", http.StatusMovedPermanently)
+				return
+			}
+			web.handleSearch(w, r)
+			return
+		} else if albumName == "tag" && media == "" {
+			// All tags, or the media having a tag
+			if !strings.HasSuffix(originalPath, "/") {
+				http.Redirect(w, r, originalPath+"/", http.StatusMovedPermanently)
+				return
+			}
+			web.handleDisplayTag(w, r, kind)
+			return
+		} else if albumName != "" {
 			if kind == "" && media == "" {
 				if !strings.HasSuffix(originalPath, "/") {
 					http.Redirect(w, r, originalPath+"/", http.StatusMovedPermanently)
@@ -230,6 +531,40 @@ func (web *WebInterface) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				}
 				web.handleDisplayAlbum(w, r, albumName)
 				return
+			} else if kind == "download" && media == "" {
+				web.handleDownloadAlbum(w, r, albumName)
+				return
+			} else if kind == "map" && media == "" {
+				web.handleDisplayMap(w, r, albumName)
+				return
+			} else if kind == "embed" && media == "" {
+				if !strings.HasSuffix(originalPath, "/") {
+					http.Redirect(w, r, originalPath+"/", http.StatusMovedPermanently)
+					return
+				}
+				web.handleEmbedAlbum(w, r, albumName)
+				return
+			} else if kind == "oembed" && media == "" {
+				web.handleOEmbed(w, r, albumName)
+				return
+			} else if kind == "feed.atom" && media == "" {
+				web.handleAlbumFeed(w, r, albumName)
+				return
+			} else if kind == "slideshow" && media == "" {
+				// The slideshow loads the album with relative URLs
+				if !strings.HasSuffix(originalPath, "/") {
+					target := GetBasePath(r) + originalPath + "/"
+					if r.URL.RawQuery != "" {
+						target += "?" + r.URL.RawQuery
+					}
+					http.Redirect(w, r, target, http.StatusMovedPermanently)
+					return
+				}
+				web.handleSlideshow(w, r, albumName)
+				return
+			} else if kind == "page" && media == "" {
+				web.handleAlbumFragment(w, r, albumName)
+				return
 			} else if kind == "raw" && media != "" {
 				web.handleGetMedia(w, r, albumName, media)
 				return
@@ -245,6 +580,15 @@ func (web *WebInterface) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			web.handleDisplayIndex(w, r)
 			return
 		}
+	} else if resource == "admin" {
+		web.serveAdmin(w, r, originalPath)
+		return
+	} else if r.Method == "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	} else if resource == "api" {
+		web.serveAPI(w, r)
+		return
 	} else if resource == "" {
 		http.Redirect(w, r, "/album/", http.StatusMovedPermanently)
 		return
@@ -1,6 +1,6 @@
-
-document.addEventListener('DOMContentLoaded', function(event) {
-    var videos = document.getElementsByTagName("video");
+// Videos play when the mouse is over them
+function playOnHover(root) {
+    var videos = root.getElementsByTagName("video");
 
     for (var i = 0; i < videos.length; i++) {
         var video = videos[i];
@@ -14,4 +14,621 @@ document.addEventListener('DOMContentLoaded', function(event) {
             event.target.pause();
         });
     }
+}
+
+document.addEventListener('DOMContentLoaded', function(event) {
+    if (document.body.classList.contains("album")) {
+        playOnHover(document);
+    }
+}, false);
+
+document.addEventListener('DOMContentLoaded', function(event) {
+    var form = document.querySelector("form.upload");
+    if (form == null) {
+        return;
+    }
+
+    var upload = function(files) {
+        // The caption must come first, the files are processed as they arrive
+        var data = new FormData();
+        data.append("caption", form.querySelector("input[name=caption]").value);
+        for (var i = 0; i < files.length; i++) {
+            data.append("file", files[i]);
+        }
+
+        form.classList.add("uploading");
+        fetch(form.action, {
+            method: "POST",
+            body: data,
+            credentials: "same-origin",
+            headers: { "Accept": "application/json" }
+        }).then(function(response) {
+            if (!response.ok) {
+                throw new Error(response.statusText);
+            }
+            window.location.reload();
+        }).catch(function(error) {
+            form.classList.remove("uploading");
+            alert(form.dataset.error + " (" + error.message + ")");
+        });
+    };
+
+    form.addEventListener("submit", function(event) {
+        event.preventDefault();
+        upload(form.querySelector("input[type=file]").files);
+    });
+
+    // The whole page is a drop zone
+    document.body.addEventListener("dragover", function(event) {
+        event.preventDefault();
+        form.classList.add("dragging");
+    });
+
+    document.body.addEventListener("dragleave", function(event) {
+        form.classList.remove("dragging");
+    });
+
+    document.body.addEventListener("drop", function(event) {
+        event.preventDefault();
+        form.classList.remove("dragging");
+        upload(event.dataTransfer.files);
+    });
+}, false);
+
+document.addEventListener('DOMContentLoaded', function(event) {
+    var form = document.querySelector("form.selection");
+    var select = document.querySelector("button.select");
+    if (form == null || select == null) {
+        return;
+    }
+
+    // Selection mode requires JavaScript
+    document.body.classList.add("scripted");
+
+    var reset = function() {
+        var checkboxes = form.querySelectorAll("input[name=media]");
+        for (var i = 0; i < checkboxes.length; i++) {
+            checkboxes[i].checked = false;
+            checkboxes[i].parentNode.classList.remove("selected");
+        }
+        document.body.classList.remove("selecting");
+    };
+
+    select.addEventListener("click", function(event) {
+        document.body.classList.add("selecting");
+    });
+
+    form.querySelector("button.cancel").addEventListener("click", reset);
+
+    // Events are delegated to the form since media are added while scrolling
+    form.addEventListener("change", function(event) {
+        if (event.target.name == "media") {
+            event.target.parentNode.classList.toggle("selected", event.target.checked);
+        }
+    });
+
+    // In selection mode, clicking a media selects it instead of opening it
+    form.addEventListener("click", function(event) {
+        var link = event.target.closest("li a");
+        if (link == null || !document.body.classList.contains("selecting")) {
+            return;
+        }
+
+        event.preventDefault();
+        var checkbox = link.parentNode.querySelector("input[name=media]");
+        checkbox.checked = !checkbox.checked;
+        checkbox.dispatchEvent(new Event("change", { bubbles: true }));
+    });
+
+    form.addEventListener("submit", function(event) {
+        if (form.querySelector("input[name=media]:checked") == null) {
+            event.preventDefault();
+            return;
+        }
+
+        // Leave the selection mode once the download has started
+        setTimeout(reset, 0);
+    });
+}, false);
+
+// Infinite scroll: the next page is loaded when the pagination links become
+// visible. Without JavaScript, the links are used instead.
+document.addEventListener('DOMContentLoaded', function(event) {
+    var next = document.querySelector("nav.pages a[rel=next]");
+    var list = document.querySelector("body.album form.selection ul");
+    if (next == null || list == null || !("IntersectionObserver" in window)) {
+        return;
+    }
+
+    var nav = next.parentNode;
+    var after = next.dataset.after;
+    var loading = false;
+    next.remove();
+
+    var observer = new IntersectionObserver(function(entries) {
+        if (!entries[0].isIntersecting || loading || after == "") {
+            return;
+        }
+
+        loading = true;
+        // Keep the filters of the album page, such as the highlights
+        var query = new URLSearchParams(window.location.search);
+        query.delete("before");
+        query.set("after", after);
+        fetch("page/?" + query.toString(), {
+            credentials: "same-origin",
+            headers: { "Accept": "application/json" }
+        }).then(function(response) {
+            if (!response.ok) {
+                throw new Error(response.statusText);
+            }
+            return response.json();
+        }).then(function(fragment) {
+            // New media are inserted before the filler item
+            var template = document.createElement("template");
+            template.innerHTML = fragment.html;
+            playOnHover(template.content);
+            list.insertBefore(template.content, list.lastElementChild);
+
+            after = fragment.after || "";
+            loading = false;
+            if (after == "") {
+                observer.disconnect();
+            } else {
+                // Observe again, in case the end of the page is still visible
+                observer.unobserve(nav);
+                observer.observe(nav);
+            }
+        }).catch(function(error) {
+            // Fallback to a regular link
+            observer.disconnect();
+            nav.appendChild(next);
+        });
+    }, { rootMargin: "100% 0px" });
+
+    observer.observe(nav);
+}, false);
+
+// Arrow keys and swipes move between the media of an album
+document.addEventListener('DOMContentLoaded', function(event) {
+    if (!document.body.classList.contains("media")) {
+        return;
+    }
+
+    var go = function(rel) {
+        var link = document.querySelector("nav.media a[rel=" + rel + "]");
+        if (link != null) {
+            window.location.href = link.href;
+        }
+    };
+
+    document.addEventListener("keydown", function(event) {
+        // Keys typed in the comment form are not shortcuts
+        if (event.target.closest("input, textarea") != null) {
+            return;
+        }
+
+        if (event.key == "ArrowLeft") {
+            go("prev");
+        } else if (event.key == "ArrowRight") {
+            go("next");
+        } else if (event.key == "Escape") {
+            window.location.href = "../../";
+        }
+    });
+
+    var startX = null, startY = null;
+    document.addEventListener("touchstart", function(event) {
+        if (event.touches.length == 1) {
+            startX = event.touches[0].clientX;
+            startY = event.touches[0].clientY;
+        }
+    }, { passive: true });
+
+    document.addEventListener("touchend", function(event) {
+        if (startX == null) {
+            return;
+        }
+
+        var dx = event.changedTouches[0].clientX - startX;
+        var dy = event.changedTouches[0].clientY - startY;
+        startX = startY = null;
+
+        // Only clear horizontal swipes count
+        if (Math.abs(dx) > 50 && Math.abs(dx) > 2 * Math.abs(dy)) {
+            go(dx > 0 ? "prev" : "next");
+        }
+    });
+}, false);
+
+// Slideshow: photos stay on screen for the configured interval while videos
+// play through before moving to the next media.
+document.addEventListener('DOMContentLoaded', function(event) {
+    if (!document.body.classList.contains("slideshow")) {
+        return;
+    }
+
+    var slides = JSON.parse(document.getElementById("slides").textContent) || [];
+    var interval = parseInt(document.body.dataset.interval, 10) * 1000;
+    var captions = document.body.dataset.captions == "true";
+    var container = document.querySelector("div.slide");
+    var caption = document.querySelector("p.caption");
+    var current = -1, timer = null, paused = false;
+
+    if (document.body.dataset.shuffle == "true") {
+        for (var i = slides.length - 1; i > 0; i--) {
+            var j = Math.floor(Math.random() * (i + 1));
+            var tmp = slides[i];
+            slides[i] = slides[j];
+            slides[j] = tmp;
+        }
+    }
+
+    var preload = function(index) {
+        var slide = slides[index % slides.length];
+        if (slide.type == "photo") {
+            new Image().src = "../raw/" + slide.photo;
+        }
+    };
+
+    var show = function(index) {
+        if (slides.length == 0) {
+            return;
+        }
+
+        clearTimeout(timer);
+        current = (index + slides.length) % slides.length;
+        var slide = slides[current];
+        var element;
+        if (slide.type == "video") {
+            element = document.createElement("video");
+            element.src = "../raw/" + slide.video;
+            if (slide.photo) {
+                element.poster = "../raw/" + slide.photo;
+            }
+            element.autoplay = true;
+            element.muted = true;
+            element.playsInline = true;
+            element.addEventListener("ended", function() {
+                if (!paused) {
+                    show(current + 1);
+                }
+            });
+            element.addEventListener("error", function() {
+                schedule();
+            });
+        } else {
+            element = document.createElement("img");
+            element.src = "../raw/" + slide.photo;
+            schedule();
+        }
+
+        container.replaceChildren(element);
+        caption.textContent = captions ? (slide.caption || "") : "";
+        preload(current + 1);
+    };
+
+    var schedule = function() {
+        clearTimeout(timer);
+        if (!paused) {
+            timer = setTimeout(function() { show(current + 1); }, interval);
+        }
+    };
+
+    var togglePause = function() {
+        paused = !paused;
+        var video = container.querySelector("video");
+        if (video != null) {
+            paused ? video.pause() : video.play();
+        }
+        if (paused) {
+            clearTimeout(timer);
+        } else if (video == null) {
+            schedule();
+        }
+    };
+
+    document.addEventListener("keydown", function(event) {
+        if (event.key == "ArrowLeft") {
+            show(current - 1);
+        } else if (event.key == "ArrowRight") {
+            show(current + 1);
+        } else if (event.key == " ") {
+            event.preventDefault();
+            togglePause();
+        } else if (event.key == "Escape" && document.fullscreenElement == null) {
+            window.location.href = "../";
+        }
+    });
+
+    // Full screen requires a user interaction
+    document.body.addEventListener("click", function(event) {
+        if (document.fullscreenElement == null && document.documentElement.requestFullscreen) {
+            document.documentElement.requestFullscreen();
+        } else {
+            togglePause();
+        }
+    });
+
+    show(0);
+}, false);
+
+// Minimal slippy map, displaying the geotagged media on top of the tiles of
+// any server following the OpenStreetMap URL scheme. Nearby media are
+// clustered, depending on the zoom level. The route of the album and the
+// places shared on the way are drawn too.
+document.addEventListener('DOMContentLoaded', function(event) {
+    var container = document.querySelector("div.map");
+    if (container == null) {
+        return;
+    }
+
+    var TILE_SIZE = 256, CLUSTER_SIZE = 60;
+    var tileURL = container.dataset.tiles;
+    var maxZoom = parseInt(container.dataset.maxZoom, 10) || 19;
+    var tiles = document.createElement("div");
+    var markers = document.createElement("div");
+    var route = document.createElementNS("http://www.w3.org/2000/svg", "svg");
+    tiles.className = markers.className = "layer";
+    route.setAttribute("class", "route");
+    container.insertBefore(markers, container.firstChild);
+    container.insertBefore(route, markers);
+    container.insertBefore(tiles, route);
+
+    var features = [], places = [], routes = [];
+    var zoom = 2;
+    var center = { x: 0.5, y: 0.5 }; // in world coordinates, between 0 and 1
+
+    // Web Mercator projection
+    var project = function(lon, lat) {
+        var sin = Math.sin(lat * Math.PI / 180);
+        return {
+            x: (lon + 180) / 360,
+            y: 0.5 - Math.log((1 + sin) / (1 - sin)) / (4 * Math.PI)
+        };
+    };
+
+    var worldSize = function() {
+        return TILE_SIZE * Math.pow(2, zoom);
+    };
+
+    // Screen coordinates of the top left corner, in pixels at the current zoom
+    var origin = function() {
+        return {
+            x: center.x * worldSize() - container.clientWidth / 2,
+            y: center.y * worldSize() - container.clientHeight / 2
+        };
+    };
+
+    var renderTiles = function() {
+        var o = origin();
+        var count = Math.pow(2, zoom);
+        var fragment = document.createDocumentFragment();
+        for (var tx = Math.floor(o.x / TILE_SIZE); tx * TILE_SIZE < o.x + container.clientWidth; tx++) {
+            for (var ty = Math.floor(o.y / TILE_SIZE); ty * TILE_SIZE < o.y + container.clientHeight; ty++) {
+                if (ty < 0 || ty >= count) {
+                    continue;
+                }
+
+                var img = document.createElement("img");
+                img.className = "tile";
+                img.alt = "";
+                img.src = tileURL.replace("{z}", zoom)
+                    .replace("{x}", ((tx % count) + count) % count)
+                    .replace("{y}", ty)
+                    .replace("{s}", "abc"[Math.abs(tx + ty) % 3]);
+                img.style.left = (tx * TILE_SIZE - o.x) + "px";
+                img.style.top = (ty * TILE_SIZE - o.y) + "px";
+                fragment.appendChild(img);
+            }
+        }
+        tiles.replaceChildren(fragment);
+    };
+
+    var renderMarkers = function() {
+        var o = origin();
+        var size = worldSize();
+        var clusters = {};
+        features.forEach(function(feature) {
+            var x = feature.point.x * size, y = feature.point.y * size;
+            var key = Math.floor(x / CLUSTER_SIZE) + "," + Math.floor(y / CLUSTER_SIZE);
+            var cluster = clusters[key] || (clusters[key] = { x: 0, y: 0, features: [] });
+            cluster.x += x;
+            cluster.y += y;
+            cluster.features.push(feature);
+        });
+
+        var fragment = document.createDocumentFragment();
+        Object.keys(clusters).forEach(function(key) {
+            var cluster = clusters[key];
+            var count = cluster.features.length;
+            var first = cluster.features[0].properties;
+            var marker = document.createElement("a");
+            marker.className = "marker";
+            marker.href = first.url;
+            marker.title = first.caption || "";
+            marker.style.left = (cluster.x / count - o.x) + "px";
+            marker.style.top = (cluster.y / count - o.y) + "px";
+            if (first.thumbnail) {
+                var img = document.createElement("img");
+                img.src = first.thumbnail;
+                img.alt = "";
+                img.loading = "lazy";
+                marker.appendChild(img);
+            }
+            if (count > 1) {
+                var badge = document.createElement("span");
+                badge.textContent = count;
+                marker.appendChild(badge);
+
+                // Clusters are zoomed in instead of being opened
+                marker.addEventListener("click", function(event) {
+                    event.preventDefault();
+                    center = { x: cluster.x / count / size, y: cluster.y / count / size };
+                    setZoom(zoom + 2);
+                });
+            }
+            fragment.appendChild(marker);
+        });
+        markers.replaceChildren(fragment);
+    };
+
+    var renderRoute = function() {
+        var o = origin();
+        var size = worldSize();
+        route.setAttribute("width", container.clientWidth);
+        route.setAttribute("height", container.clientHeight);
+
+        var fragment = document.createDocumentFragment();
+        routes.forEach(function(points) {
+            var line = document.createElementNS("http://www.w3.org/2000/svg", "polyline");
+            line.setAttribute("points", points.map(function(point) {
+                return (point.x * size - o.x) + "," + (point.y * size - o.y);
+            }).join(" "));
+            fragment.appendChild(line);
+        });
+        places.forEach(function(place) {
+            var dot = document.createElementNS("http://www.w3.org/2000/svg", "circle");
+            var title = document.createElementNS("http://www.w3.org/2000/svg", "title");
+            dot.setAttribute("cx", place.point.x * size - o.x);
+            dot.setAttribute("cy", place.point.y * size - o.y);
+            dot.setAttribute("r", 6);
+            title.textContent = place.properties.title;
+            dot.appendChild(title);
+            fragment.appendChild(dot);
+        });
+        route.replaceChildren(fragment);
+    };
+
+    var render = function() {
+        renderTiles();
+        renderRoute();
+        renderMarkers();
+    };
+
+    var setZoom = function(z) {
+        zoom = Math.max(0, Math.min(maxZoom, z));
+        render();
+    };
+
+    // Choose the highest zoom level showing all the media
+    var fitBounds = function() {
+        var minX = 1, minY = 1, maxX = 0, maxY = 0;
+        var points = features.concat(places).map(function(feature) { return feature.point; });
+        routes.forEach(function(line) { points = points.concat(line); });
+        points.forEach(function(point) {
+            minX = Math.min(minX, point.x);
+            minY = Math.min(minY, point.y);
+            maxX = Math.max(maxX, point.x);
+            maxY = Math.max(maxY, point.y);
+        });
+        center = { x: (minX + maxX) / 2, y: (minY + maxY) / 2 };
+
+        var z = Math.min(maxZoom, 15);
+        while (z > 0 && ((maxX - minX) * TILE_SIZE * Math.pow(2, z) > container.clientWidth - 2 * CLUSTER_SIZE ||
+                         (maxY - minY) * TILE_SIZE * Math.pow(2, z) > container.clientHeight - 2 * CLUSTER_SIZE)) {
+            z--;
+        }
+        setZoom(z);
+    };
+
+    container.querySelector("button.zoom-in").addEventListener("click", function() { setZoom(zoom + 1); });
+    container.querySelector("button.zoom-out").addEventListener("click", function() { setZoom(zoom - 1); });
+
+    container.addEventListener("wheel", function(event) {
+        // Maps embedded in a page only zoom with Ctrl, to let the page scroll
+        if (!document.body.classList.contains("map") && !event.ctrlKey) {
+            return;
+        }
+        event.preventDefault();
+        setZoom(zoom + (event.deltaY < 0 ? 1 : -1));
+    }, { passive: false });
+
+    // Drag to pan
+    var drag = null;
+    container.addEventListener("pointerdown", function(event) {
+        if (event.target.closest("a, button") != null) {
+            return;
+        }
+        drag = { x: event.clientX, y: event.clientY };
+        container.setPointerCapture(event.pointerId);
+    });
+    container.addEventListener("pointermove", function(event) {
+        if (drag == null) {
+            return;
+        }
+        center.x -= (event.clientX - drag.x) / worldSize();
+        center.y -= (event.clientY - drag.y) / worldSize();
+        center.y = Math.max(0, Math.min(1, center.y));
+        drag = { x: event.clientX, y: event.clientY };
+        render();
+    });
+    container.addEventListener("pointerup", function(event) {
+        drag = null;
+    });
+
+    window.addEventListener("resize", render);
+
+    fetch(container.dataset.geojson, {
+        credentials: "same-origin",
+        headers: { "Accept": "application/json" }
+    }).then(function(response) {
+        if (!response.ok) {
+            throw new Error(response.statusText);
+        }
+        return response.json();
+    }).then(function(collection) {
+        collection.features.forEach(function(feature) {
+            var coordinates = feature.geometry.coordinates;
+            if (feature.geometry.type == "LineString") {
+                routes.push(coordinates.map(function(c) { return project(c[0], c[1]); }));
+                return;
+            }
+
+            feature.point = project(coordinates[0], coordinates[1]);
+            if (feature.properties.kind == "place") {
+                places.push(feature);
+            } else {
+                features.push(feature);
+            }
+        });
+        if (collection.features.length == 0) {
+            container.classList.add("empty");
+            render();
+            return;
+        }
+        fitBounds();
+    }).catch(function(error) {
+        container.classList.add("empty");
+        render();
+    });
+}, false);
+
+// Favorites are toggled without reloading the media page
+document.addEventListener('DOMContentLoaded', function(event) {
+    var form = document.querySelector("form.favorite");
+    if (form == null) {
+        return;
+    }
+
+    form.addEventListener("submit", function(event) {
+        event.preventDefault();
+        fetch(form.action, {
+            method: "POST",
+            credentials: "same-origin",
+            headers: { "Accept": "application/json" }
+        }).then(function(response) {
+            if (!response.ok) {
+                throw new Error(response.statusText);
+            }
+            return response.json();
+        }).then(function(state) {
+            var button = form.querySelector("button");
+            button.classList.toggle("active", state.favorite);
+            button.querySelector("span.count").textContent = state.count;
+        }).catch(function(error) {
+            // Fallback to a regular form submission
+            form.submit();
+        });
+    });
 }, false);
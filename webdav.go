@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+// WebDAVFileSystem exposes the MediaStore as a read-only file system: albums
+// are folders named after their date and title, and media are files named
+// after their date and caption (as in the ZIP archives).
+//
+// A WebDAVFileSystem serves a single request: the folders and file names are
+// computed once, on first use, and looked up from there.
+type WebDAVFileSystem struct {
+	MediaStore *MediaStore
+
+	once   sync.Once
+	albums []*davAlbum
+	byName map[string]*davAlbum
+	err    error
+}
+
+// A davAlbum is an album as seen through WebDAV
+type davAlbum struct {
+	Name   string // name of the WebDAV folder
+	Folder string // folder of the MediaStore
+	Album  *Album
+	Files  map[string]string    // file of the MediaStore, by friendly name
+	Dates  map[string]time.Time // date of the media, by file of the MediaStore
+}
+
+type davFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (fi davFileInfo) Name() string       { return fi.name }
+func (fi davFileInfo) Size() int64        { return fi.size }
+func (fi davFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi davFileInfo) IsDir() bool        { return fi.dir }
+func (fi davFileInfo) Sys() interface{}   { return nil }
+
+func (fi davFileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
+
+// A davDir is an open folder, either the root or an album
+type davDir struct {
+	info     davFileInfo
+	children []os.FileInfo
+	pos      int
+}
+
+func (d *davDir) Close() error                                 { return nil }
+func (d *davDir) Read(p []byte) (int, error)                   { return 0, os.ErrInvalid }
+func (d *davDir) Seek(offset int64, whence int) (int64, error) { return 0, os.ErrInvalid }
+func (d *davDir) Write(p []byte) (int, error)                  { return 0, os.ErrPermission }
+func (d *davDir) Stat() (os.FileInfo, error)                   { return d.info, nil }
+
+func (d *davDir) Readdir(count int) ([]os.FileInfo, error) {
+	remaining := d.children[d.pos:]
+	if count <= 0 {
+		d.pos = len(d.children)
+		return remaining, nil
+	}
+
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+	d.pos += count
+	return remaining[:count], nil
+}
+
+// A davFile is an open media file, under its friendly name
+type davFile struct {
+	*os.File
+	info davFileInfo
+}
+
+func (f *davFile) Write(p []byte) (int, error)              { return 0, os.ErrPermission }
+func (f *davFile) Stat() (os.FileInfo, error)               { return f.info, nil }
+func (f *davFile) Readdir(count int) ([]os.FileInfo, error) { return nil, os.ErrInvalid }
+
+// davFolderName returns the name of the folder of an album: its date and
+// title
+func davFolderName(album *Album) string {
+	name := cleanFileName(album.Title)
+	if !album.Date.IsZero() {
+		name = strings.TrimSpace(album.Date.Format("2006-01-02") + " " + name)
+	}
+	if name == "" {
+		name = "latest"
+	}
+
+	return name
+}
+
+// index lists the albums of the MediaStore, with unique folder names, and
+// the friendly names of their files
+func (dav *WebDAVFileSystem) index() error {
+	dav.once.Do(func() {
+		dav.albums, dav.byName, dav.err = dav.buildIndex()
+	})
+
+	return dav.err
+}
+
+func (dav *WebDAVFileSystem) buildIndex() ([]*davAlbum, map[string]*davAlbum, error) {
+	files, err := ioutil.ReadDir(dav.MediaStore.StoreLocation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var albums []*davAlbum
+	byName := make(map[string]*davAlbum)
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+
+		album, err := dav.MediaStore.getCachedAlbum(file.Name())
+		if err != nil {
+			log.Printf("WebDAV: Cannot extract album info for '%s'", file.Name())
+			continue
+		}
+
+		base := davFolderName(album)
+		name := base
+		for i := 2; byName[name] != nil; i++ {
+			name = fmt.Sprintf("%s (%d)", base, i)
+		}
+
+		davAlbum := &davAlbum{
+			Name:   name,
+			Folder: file.Name(),
+			Album:  album,
+			Files:  make(map[string]string),
+			Dates:  make(map[string]time.Time),
+		}
+		for file, friendlyName := range friendlyNames(album.Media) {
+			davAlbum.Files[friendlyName] = file
+		}
+		for _, media := range album.Media {
+			for _, f := range media.Files {
+				davAlbum.Dates[f] = media.TakenDate()
+			}
+		}
+
+		albums = append(albums, davAlbum)
+		byName[name] = davAlbum
+	}
+
+	return albums, byName, nil
+}
+
+// resolve returns the album and the file (in the MediaStore) targeted by a
+// WebDAV path. The root folder has no album and an album folder has no file.
+func (dav *WebDAVFileSystem) resolve(name string) (*davAlbum, string, string, error) {
+	err := dav.index()
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	parts := strings.Split(strings.Trim(path.Clean("/"+name), "/"), "/")
+	if parts[0] == "" {
+		return nil, "", "", nil
+	}
+	if len(parts) > 2 {
+		return nil, "", "", os.ErrNotExist
+	}
+
+	album, ok := dav.byName[parts[0]]
+	if !ok {
+		return nil, "", "", os.ErrNotExist
+	}
+	if len(parts) == 1 {
+		return album, "", "", nil
+	}
+
+	file, ok := album.Files[parts[1]]
+	if !ok {
+		return nil, "", "", os.ErrNotExist
+	}
+
+	return album, file, parts[1], nil
+}
+
+func (dav *WebDAVFileSystem) albumInfo(album *davAlbum) davFileInfo {
+	return davFileInfo{name: album.Name, modTime: album.Album.Date, dir: true}
+}
+
+func (dav *WebDAVFileSystem) fileInfo(album *davAlbum, file string, friendlyName string) (davFileInfo, error) {
+	stat, err := os.Stat(filepath.Join(dav.MediaStore.StoreLocation, album.Folder, file))
+	if err != nil {
+		return davFileInfo{}, err
+	}
+
+	// Like in the ZIP archives, files are dated when the media was taken
+	info := davFileInfo{name: friendlyName, size: stat.Size(), modTime: stat.ModTime()}
+	if date, ok := album.Dates[file]; ok {
+		info.modTime = date
+	}
+
+	return info, nil
+}
+
+func (dav *WebDAVFileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	album, file, friendlyName, err := dav.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case album == nil:
+		return davFileInfo{name: "/", dir: true}, nil
+	case file == "":
+		return dav.albumInfo(album), nil
+	default:
+		return dav.fileInfo(album, file, friendlyName)
+	}
+}
+
+func (dav *WebDAVFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, os.ErrPermission
+	}
+
+	album, file, friendlyName, err := dav.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if album == nil {
+		dir := &davDir{info: davFileInfo{name: "/", dir: true}}
+		for _, album := range dav.albums {
+			dir.children = append(dir.children, dav.albumInfo(album))
+		}
+		return dir, nil
+	}
+
+	if file == "" {
+		dir := &davDir{info: dav.albumInfo(album)}
+		for friendlyName, file := range album.Files {
+			info, err := dav.fileInfo(album, file, friendlyName)
+			if err != nil {
+				log.Printf("WebDAV: Cannot stat '%s': %s", file, err)
+				continue
+			}
+			dir.children = append(dir.children, info)
+		}
+		sort.Slice(dir.children, func(i, j int) bool {
+			return dir.children[i].Name() < dir.children[j].Name()
+		})
+		return dir, nil
+	}
+
+	info, err := dav.fileInfo(album, file, friendlyName)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, _, err := dav.MediaStore.OpenFile(album.Folder, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &davFile{File: fd, info: info}, nil
+}
+
+func (dav *WebDAVFileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return os.ErrPermission
+}
+
+func (dav *WebDAVFileSystem) RemoveAll(ctx context.Context, name string) error {
+	return os.ErrPermission
+}
+
+func (dav *WebDAVFileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	return os.ErrPermission
+}
+
+// serveWebDAV exposes the MediaStore as read-only WebDAV under /webdav/
+func (web *WebInterface) serveWebDAV(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "OPTIONS", "GET", "HEAD", "PROPFIND":
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	handler := &webdav.Handler{
+		Prefix:     "/webdav",
+		FileSystem: &WebDAVFileSystem{MediaStore: web.MediaStore},
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(r *http.Request, err error) {
+			if err != nil && !os.IsNotExist(err) {
+				log.Printf("WebDAV: %s %s: %s", r.Method, r.URL.Path, err)
+			}
+		},
+	}
+	handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestWebDAV(t *testing.T) {
+	tmp := createTempDir(t)
+	defer tmp.cleanup(t)
+
+	store := createTestMediaStore(t, tmp)
+
+	createTestAlbum(t, store, "Holidays")
+	id := addTestPhoto(t, store, time.Date(2020, 7, 14, 12, 30, 0, 0, time.Local), "At the beach", "")
+
+	album, err := store.GetCurrentAlbum()
+	if err != nil {
+		t.Fatalf("GetCurrentAlbum(): error %s", err)
+	}
+	folder := davFolderName(album)
+	assert.Equal(t, strings.HasSuffix(folder, " Holidays"), true, "folders are named by date and title")
+
+	web := &WebInterface{MediaStore: store}
+	user := &WebUser{Username: "alice", Type: TypeWebDAVUser, Scopes: []string{ScopeRead}}
+	do := func(method string, target string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		r.Header.Set("Depth", "1")
+		w := httptest.NewRecorder()
+		web.ServeHTTP(w, withWebUser(r, user, ""))
+		return w
+	}
+
+	w := do("PROPFIND", "/webdav/")
+	assert.Equal(t, w.Code, http.StatusMultiStatus, "root folder")
+	assert.Equal(t, strings.Contains(w.Body.String(), "<D:href>/webdav/"+strings.ReplaceAll(folder, " ", "%20")+"/</D:href>"), true, "albums are folders")
+
+	w = do("PROPFIND", "/webdav/"+url.PathEscape(folder)+"/")
+	assert.Equal(t, w.Code, http.StatusMultiStatus, "album folder")
+	assert.Equal(t, strings.Contains(w.Body.String(), "2020-07-14%2012.30.00%20At%20the%20beach.jpeg"), true, "files have friendly names")
+
+	w = do("GET", "/webdav/"+url.PathEscape(folder)+"/"+url.PathEscape("2020-07-14 12.30.00 At the beach.jpeg"))
+	assert.Equal(t, w.Code, http.StatusOK, "file download")
+	assert.Equal(t, w.Body.String(), "JPEG File", "file content")
+
+	w = do("GET", "/webdav/"+url.PathEscape(folder)+"/"+id+".jpeg")
+	assert.Equal(t, w.Code, http.StatusNotFound, "files are only reachable by their friendly name")
+
+	for _, method := range []string{"PUT", "DELETE", "MKCOL", "MOVE", "COPY", "PROPPATCH", "LOCK"} {
+		w = do(method, "/webdav/"+url.PathEscape(folder)+"/new.jpeg")
+		assert.Equal(t, w.Code, http.StatusMethodNotAllowed, method+" is not allowed")
+	}
+}
+
+func TestWebDAVAuthentication(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt.GenerateFromPassword(): %s", err)
+	}
+
+	var served *http.Request
+	securityFrontend := &SecurityFrontend{
+		WebDAVUsers: map[string]string{"alice": string(hash)},
+		Protected: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			served = r
+		}),
+	}
+	propfind := func(username, password string) int {
+		served = nil
+		r := httptest.NewRequest("PROPFIND", "/webdav/", nil)
+		if username != "" {
+			r.SetBasicAuth(username, password)
+		}
+		w := httptest.NewRecorder()
+		securityFrontend.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, propfind("", ""), http.StatusUnauthorized, "anonymous access is denied")
+	assert.Equal(t, propfind("alice", "wrong"), http.StatusUnauthorized, "wrong password")
+	assert.Equal(t, propfind("bob", "secret"), http.StatusUnauthorized, "unknown user")
+	assert.Equal(t, propfind("Alice", "secret"), http.StatusOK, "valid password")
+	assert.Equal(t, GetWebUser(served).Type, TypeWebDAVUser, "WebDAV user")
+}